@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/RobinThrift/toolfetcher"
+)
+
+const usage = `Usage: toolfetcher <command> [flags] [args]
+
+Commands:
+  install [tools...]     install the given tools, or all tools in the version file
+  list                   list all tools in the version file and their install status
+  which <tool>           print the path of an installed tool
+  exec <tool> -- [args]  install a tool if necessary and run it with the given arguments
+  prune                  remove tool versions from the store that are no longer referenced
+  check                  fail if any tool in the version file is not installed
+
+Run 'toolfetcher <command> -h' for the flags of a command.
+`
+
+// errInvalidUsage is returned for invalid flags, which the flag set already printed along with the usage.
+var errInvalidUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(context.Background(), os.Args[1:])
+	if err == nil {
+		return
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+
+	if errors.Is(err, errInvalidUsage) {
+		os.Exit(2)
+	}
+
+	log.Fatal(err)
+}
+
+func run(ctx context.Context, args []string) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer cancel()
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(os.Stderr, usage)
+		return nil
+	}
+
+	cmd, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("toolfetcher "+cmd, flag.ContinueOnError)
+
+	fetcher := &toolfetcher.ToolFetcher{}
+	flags.StringVar(&fetcher.VersionFile, "versionfile", "TOOL_VERSIONS", "path to version file")
+	flags.StringVar(&fetcher.BinDir, "to", ".bin", "bin dir")
+	flags.StringVar(&fetcher.StoreDir, "store", "", "store dir (default <bin dir>/.store)")
+	flags.BoolVar(&fetcher.SharedStore, "shared", false, "use a store in the user cache dir shared between projects, unless -store is set")
+	flags.StringVar(&fetcher.RecipeFile, "recipes", "", "path to an optional YAML or JSON recipe file")
+	flags.StringVar(&fetcher.LockFile, "lockfile", "", "path to the lock file (default <version file>.lock)")
+	flags.BoolVar(&fetcher.Frozen, "frozen", false, "fail instead of writing new lock file entries")
+	flags.IntVar(&fetcher.Concurrency, "concurrency", 1, "number of tools to install in parallel")
+	httpTimeout := flags.Duration("http-timeout", 0, "timeout of a single HTTP request including reading the body, 0 means no timeout")
+	httpRetries := flags.Int("http-retries", toolfetcher.DefaultRetryPolicy.MaxAttempts-1, "number of retries of requests failing with a transient error")
+
+	var pruneOpts toolfetcher.PruneOptions
+	if cmd == "prune" {
+		flags.IntVar(&pruneOpts.KeepVersions, "keep-versions", 0, "number of unreferenced versions to keep per tool")
+		flags.DurationVar(&pruneOpts.KeepUsedWithin, "keep-used-within", 0, "keep unreferenced tools used within this duration, e.g. 720h")
+		flags.BoolVar(&pruneOpts.DryRun, "dry-run", false, "only print what would be removed")
+	}
+
+	// the flag set already printed the error and the usage
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return errInvalidUsage
+	}
+
+	if *httpTimeout > 0 {
+		fetcher.HTTPClient = &http.Client{Timeout: *httpTimeout}
+	}
+
+	fetcher.Retry = toolfetcher.DefaultRetryPolicy
+	fetcher.Retry.MaxAttempts = max(*httpRetries, 0) + 1
+
+	switch cmd {
+	case "install":
+		return install(ctx, fetcher, flags.Args())
+	case "list":
+		return list(ctx, fetcher)
+	case "which":
+		return which(ctx, fetcher, flags.Args())
+	case "exec":
+		return execTool(ctx, fetcher, flags.Args())
+	case "prune":
+		return prune(ctx, fetcher, pruneOpts)
+	case "check":
+		return fetcher.Check(ctx)
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+
+	return fmt.Errorf("unknown command '%s'", cmd)
+}
+
+func install(ctx context.Context, fetcher *toolfetcher.ToolFetcher, toolnames []string) error {
+	defer observeProgress(fetcher, os.Stdout)()
+
+	if len(toolnames) == 0 {
+		return fetcher.FetchAll(ctx)
+	}
+
+	return fetcher.FetchTools(ctx, toolnames)
+}
+
+func list(ctx context.Context, fetcher *toolfetcher.ToolFetcher) error {
+	statuses, err := fetcher.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSOURCE\tSTATUS")
+
+	for _, status := range statuses {
+		var state string
+		switch {
+		case status.Err != nil:
+			state = status.Err.Error()
+		case status.Installed:
+			state = "installed"
+		case status.InStore:
+			state = "not linked"
+		default:
+			state = "not installed"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Name, status.Version, status.Source, state)
+	}
+
+	return w.Flush()
+}
+
+func which(ctx context.Context, fetcher *toolfetcher.ToolFetcher, args []string) error {
+	if len(args) != 1 {
+		return errors.New("invalid usage: expected exactly one tool name")
+	}
+
+	statuses, err := fetcher.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.Name != args[0] {
+			continue
+		}
+
+		if !status.Installed {
+			return fmt.Errorf("%s: %w at version %s", status.Name, toolfetcher.ErrNotInstalled, status.Version)
+		}
+
+		binPath, err := filepath.Abs(status.BinPath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(binPath)
+
+		return nil
+	}
+
+	return fmt.Errorf("%w '%s'", toolfetcher.ErrUnknownTool, args[0])
+}
+
+func execTool(ctx context.Context, fetcher *toolfetcher.ToolFetcher, args []string) error {
+	if len(args) == 0 {
+		return errors.New("invalid usage: missing tool name")
+	}
+
+	toolname, toolArgs := args[0], args[1:]
+	if len(toolArgs) != 0 && toolArgs[0] == "--" {
+		toolArgs = toolArgs[1:]
+	}
+
+	// stdout belongs to the tool, e.g. when it is redirected into a file
+	flush := observeProgress(fetcher, os.Stderr)
+	err := fetcher.Fetch(ctx, toolname)
+	flush()
+
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, filepath.Join(fetcher.BinDir, toolname), toolArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+func prune(ctx context.Context, fetcher *toolfetcher.ToolFetcher, opts toolfetcher.PruneOptions) error {
+	report, err := fetcher.Prune(ctx, opts)
+	if report == nil {
+		return err
+	}
+
+	action := "removed"
+	if opts.DryRun {
+		action = "would remove"
+	}
+
+	for _, removed := range report.Removed {
+		fmt.Println(action, removed)
+	}
+
+	for _, kept := range report.Kept {
+		fmt.Println("kept", kept)
+	}
+
+	for _, skipped := range report.Skipped {
+		fmt.Println("skipped", skipped, "(in use)")
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher"
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_InvalidUsage(t *testing.T) {
+	stderrPath := path.Join(t.TempDir(), "stderr")
+	stderr, err := os.Create(stderrPath)
+	require.NoError(t, err)
+	defer stderr.Close()
+
+	origStderr := os.Stderr
+	os.Stderr = stderr
+	t.Cleanup(func() { os.Stderr = origStderr })
+
+	err = run(context.Background(), []string{"install", "-unknown"})
+	require.ErrorIs(t, err, errInvalidUsage)
+
+	output, err := os.ReadFile(stderrPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(output), "flag provided but not defined: -unknown"))
+}
+
+func TestExecTool_Stdout(t *testing.T) {
+	cwd := t.TempDir()
+
+	versionFile := path.Join(cwd, "TOOL_VERSIONS")
+	require.NoError(t, os.WriteFile(versionFile, []byte("tool: script://tool@1.0.0\n"), 0o644))
+
+	fetcher := &toolfetcher.ToolFetcher{
+		VersionFile: versionFile,
+		BinDir:      path.Join(cwd, ".bin"),
+		Recipes: []recipes.Recipe{{
+			Name: "tool",
+			Src:  recipes.Source{Type: "script"},
+			Test: []string{"--version"},
+		}},
+	}
+
+	fetcher.RegisterInstaller("script", toolfetcher.InstallerFunc(func(_ context.Context, tool *toolfetcher.Tool, storeDir string) (toolfetcher.InstallResult, error) {
+		err := os.WriteFile(path.Join(storeDir, tool.StoreDir()), []byte("#!/bin/sh\necho \"$@\"\n"), 0o755)
+		return toolfetcher.InstallResult{URL: "script://" + tool.VersionedName()}, err
+	}))
+
+	stdoutPath := path.Join(cwd, "stdout")
+	stdout, err := os.Create(stdoutPath)
+	require.NoError(t, err)
+	defer stdout.Close()
+
+	origStdout := os.Stdout
+	os.Stdout = stdout
+	t.Cleanup(func() { os.Stdout = origStdout })
+
+	// the first run installs the tool and runs its test
+	err = execTool(context.Background(), fetcher, []string{"tool", "--", "output"})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(stdoutPath)
+	require.NoError(t, err)
+	assert.Equal(t, "output\n", string(output))
+}
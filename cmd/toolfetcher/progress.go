@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RobinThrift/toolfetcher"
+)
+
+// progressRedrawInterval limits how often progress bars are redrawn for download progress.
+const progressRedrawInterval = 100 * time.Millisecond
+
+const progressBarWidth = 30
+
+// observeProgress reports the progress of installs to stderr and returns a function writing the final state.
+// Output of installs, e.g. of the recipes' tests, is written to stdout.
+func observeProgress(fetcher *toolfetcher.ToolFetcher, stdout io.Writer) func() {
+	observer := newObserver(os.Stderr)
+
+	fetcher.Observer = observer
+	fetcher.Stdout = observer.Writer(stdout)
+	fetcher.Stderr = observer.Writer(os.Stderr)
+
+	return observer.Flush
+}
+
+// newObserver renders progress bars to out if it is a terminal and log lines otherwise.
+func newObserver(out *os.File) progressObserver {
+	info, err := out.Stat()
+	if err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return &barObserver{out: out, lines: map[string]string{}}
+	}
+
+	return &logObserver{out: out, downloading: map[string]bool{}}
+}
+
+type progressObserver interface {
+	toolfetcher.Observer
+
+	// Writer wraps w so that output written to it doesn't interfere with the progress output.
+	Writer(w io.Writer) io.Writer
+
+	// Flush writes the latest progress.
+	Flush()
+}
+
+// logObserver writes a line per event, download progress is only logged when a download starts and ends.
+type logObserver struct {
+	mu          sync.Mutex
+	out         io.Writer
+	downloading map[string]bool
+}
+
+// log must be called with mu held, so that lines of concurrent installs don't interleave.
+func (o *logObserver) log(tool *toolfetcher.Tool, format string, args ...any) {
+	fmt.Fprintf(o.out, "%s: %s\n", tool.VersionedName(), fmt.Sprintf(format, args...))
+}
+
+func (o *logObserver) OnResolve(tool *toolfetcher.Tool, url string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.log(tool, "installing from %s", url)
+}
+
+func (o *logObserver) OnDownloadProgress(tool *toolfetcher.Tool, downloaded int64, total int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name := tool.VersionedName()
+
+	switch {
+	case !o.downloading[name]:
+		o.downloading[name] = true
+		o.log(tool, "downloading %s", formatSize(total))
+	case total >= 0 && downloaded == total:
+		delete(o.downloading, name)
+		o.log(tool, "downloaded %s", formatBytes(downloaded))
+	}
+}
+
+func (o *logObserver) OnExtract(tool *toolfetcher.Tool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.log(tool, "extracting")
+}
+
+func (o *logObserver) OnLink(tool *toolfetcher.Tool, linkPath string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.log(tool, "linked to %s", linkPath)
+}
+
+func (o *logObserver) OnTest(tool *toolfetcher.Tool, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err != nil {
+		o.log(tool, "test failed")
+		return
+	}
+
+	o.log(tool, "test passed")
+}
+
+func (o *logObserver) Writer(w io.Writer) io.Writer {
+	return w
+}
+
+func (o *logObserver) Flush() {}
+
+// barObserver renders a status line per tool and redraws all lines on every event,
+// moving the cursor back up to the first line.
+type barObserver struct {
+	mu       sync.Mutex
+	out      io.Writer
+	order    []string
+	lines    map[string]string
+	drawn    int
+	lastDraw time.Time
+}
+
+func (o *barObserver) set(tool *toolfetcher.Tool, line string, throttle bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name := tool.VersionedName()
+	if _, ok := o.lines[name]; !ok {
+		o.order = append(o.order, name)
+	}
+
+	o.lines[name] = line
+
+	if throttle && time.Since(o.lastDraw) < progressRedrawInterval {
+		return
+	}
+
+	o.clear()
+	o.draw()
+}
+
+// clear moves the cursor to the first status line and clears everything below it.
+func (o *barObserver) clear() {
+	if o.drawn > 0 {
+		fmt.Fprintf(o.out, "\x1b[%dA\x1b[J", o.drawn)
+	}
+
+	o.drawn = 0
+}
+
+func (o *barObserver) draw() {
+	for _, name := range o.order {
+		fmt.Fprintf(o.out, "%s %s\n", name, o.lines[name])
+	}
+
+	o.drawn = len(o.order)
+	o.lastDraw = time.Now()
+}
+
+func (o *barObserver) OnResolve(tool *toolfetcher.Tool, _ string) {
+	o.set(tool, "resolving", false)
+}
+
+func (o *barObserver) OnDownloadProgress(tool *toolfetcher.Tool, downloaded int64, total int64) {
+	if total <= 0 {
+		o.set(tool, "downloading "+formatBytes(downloaded), downloaded != 0)
+		return
+	}
+
+	// servers may send more than announced, or resumed downloads may report an outdated total
+	done := int(min(max(progressBarWidth*downloaded/total, 0), progressBarWidth))
+	bar := strings.Repeat("=", done) + strings.Repeat(" ", progressBarWidth-done)
+
+	o.set(tool, fmt.Sprintf("[%s] %s/%s", bar, formatBytes(downloaded), formatBytes(total)), downloaded != 0 && downloaded != total)
+}
+
+func (o *barObserver) OnExtract(tool *toolfetcher.Tool) {
+	o.set(tool, "extracting", false)
+}
+
+func (o *barObserver) OnLink(tool *toolfetcher.Tool, _ string) {
+	o.set(tool, "installed", false)
+}
+
+func (o *barObserver) OnTest(tool *toolfetcher.Tool, err error) {
+	if err != nil {
+		o.set(tool, "test failed", false)
+		return
+	}
+
+	o.set(tool, "test passed", false)
+}
+
+func (o *barObserver) Writer(w io.Writer) io.Writer {
+	return &barObserverWriter{o: o, w: w}
+}
+
+func (o *barObserver) Flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.clear()
+	o.draw()
+}
+
+// barObserverWriter writes above the status lines.
+type barObserverWriter struct {
+	o *barObserver
+	w io.Writer
+}
+
+func (w *barObserverWriter) Write(b []byte) (int, error) {
+	w.o.mu.Lock()
+	defer w.o.mu.Unlock()
+
+	w.o.clear()
+	defer w.o.draw()
+
+	return w.w.Write(b)
+}
+
+func formatSize(total int64) string {
+	if total < 0 {
+		return "(unknown size)"
+	}
+
+	return formatBytes(total)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarObserver_OnDownloadProgress(t *testing.T) {
+	tt := []struct {
+		name       string
+		downloaded int64
+		total      int64
+		expected   string
+	}{
+		{name: "Started", downloaded: 0, total: 2048, expected: "tool@1.0.0 [" + strings.Repeat(" ", 30) + "] 0 B/2.0 KiB\n"},
+		{name: "Half", downloaded: 1024, total: 2048, expected: "tool@1.0.0 [" + strings.Repeat("=", 15) + strings.Repeat(" ", 15) + "] 1.0 KiB/2.0 KiB\n"},
+		{name: "Done", downloaded: 2048, total: 2048, expected: "tool@1.0.0 [" + strings.Repeat("=", 30) + "] 2.0 KiB/2.0 KiB\n"},
+		{name: "More Than Total", downloaded: 4096, total: 2048, expected: "tool@1.0.0 [" + strings.Repeat("=", 30) + "] 4.0 KiB/2.0 KiB\n"},
+		{name: "Negative", downloaded: -1, total: 2048, expected: "tool@1.0.0 [" + strings.Repeat(" ", 30) + "] -1 B/2.0 KiB\n"},
+		{name: "Unknown Total", downloaded: 0, total: -1, expected: "tool@1.0.0 downloading 0 B\n"},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			observer := &barObserver{out: &out, lines: map[string]string{}}
+
+			observer.OnDownloadProgress(testTool("tool"), tt.downloaded, tt.total)
+			observer.Flush()
+
+			assert.Equal(t, tt.expected+"\x1b[1A\x1b[J"+tt.expected, out.String())
+		})
+	}
+}
+
+func TestBarObserver(t *testing.T) {
+	var out bytes.Buffer
+	observer := &barObserver{out: &out, lines: map[string]string{}}
+
+	a, b := testTool("a"), testTool("b")
+
+	observer.OnResolve(a, "https://example.com/a")
+	observer.OnResolve(b, "https://example.com/b")
+	_, _ = observer.Writer(&out).Write([]byte("output\n"))
+	observer.OnTest(a, errors.New("exit status 1"))
+	observer.OnLink(b, "/bin/b")
+
+	assert.Equal(t, strings.Join([]string{
+		"a@1.0.0 resolving\n",
+		"\x1b[1A\x1b[J", "a@1.0.0 resolving\nb@1.0.0 resolving\n",
+		"\x1b[2A\x1b[J", "output\n", "a@1.0.0 resolving\nb@1.0.0 resolving\n",
+		"\x1b[2A\x1b[J", "a@1.0.0 test failed\nb@1.0.0 resolving\n",
+		"\x1b[2A\x1b[J", "a@1.0.0 test failed\nb@1.0.0 installed\n",
+	}, ""), out.String())
+}
+
+func TestLogObserver(t *testing.T) {
+	var out bytes.Buffer
+	observer := &logObserver{out: &out, downloading: map[string]bool{}}
+
+	tool := testTool("tool")
+
+	observer.OnResolve(tool, "https://example.com/tool")
+	observer.OnDownloadProgress(tool, 0, 2048)
+	observer.OnDownloadProgress(tool, 1024, 2048)
+	observer.OnDownloadProgress(tool, 2048, 2048)
+	observer.OnExtract(tool)
+	observer.OnTest(tool, nil)
+	observer.OnLink(tool, "/bin/tool")
+
+	assert.Equal(t, strings.Join([]string{
+		"tool@1.0.0: installing from https://example.com/tool\n",
+		"tool@1.0.0: downloading 2.0 KiB\n",
+		"tool@1.0.0: downloaded 2.0 KiB\n",
+		"tool@1.0.0: extracting\n",
+		"tool@1.0.0: test passed\n",
+		"tool@1.0.0: linked to /bin/tool\n",
+	}, ""), out.String())
+}
+
+func testTool(name string) *toolfetcher.Tool {
+	return &toolfetcher.Tool{Name: name, Version: "1.0.0"}
+}
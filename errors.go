@@ -0,0 +1,19 @@
+package toolfetcher
+
+import (
+	"errors"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+)
+
+var ErrUnknownTool = errors.New("unknown tool")
+var ErrFetchingTools = errors.New("error fetching tools")
+var ErrChecksumMismatch = fetch.ErrChecksumMismatch
+var ErrLockMismatch = errors.New("installed tool does not match lock file")
+var ErrMissingLockEntry = errors.New("missing lock file entry")
+var ErrNotInstalled = errors.New("not installed")
+var ErrPruneSharedStore = errors.New("refusing to prune the shared store without KeepUsedWithin, it may contain tools used by other projects")
+
+// UnsafeArchiveEntryError is returned when an archive contains an entry that would be extracted
+// outside of the store, e.g. because of an absolute path, ".." components or a symlink target.
+type UnsafeArchiveEntryError = fetch.UnsafeEntryError
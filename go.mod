@@ -2,10 +2,14 @@ module github.com/RobinThrift/toolfetcher
 
 go 1.23.2
 
-require github.com/stretchr/testify v1.10.0
+require (
+	github.com/klauspost/compress v1.17.11
+	github.com/stretchr/testify v1.10.0
+	github.com/ulikunitz/xz v0.5.12
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
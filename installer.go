@@ -0,0 +1,110 @@
+package toolfetcher
+
+import (
+	"context"
+	"path"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/RobinThrift/toolfetcher/internal/installer"
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+// Installer installs a tool into storeDir. It must create the file or directory
+// storeDir/tool.StoreDir(), containing the binary at storeDir/tool.BinPath().
+// storeDir is a staging directory that is moved into the store once the install succeeded,
+// the parent directory of storeDir/tool.StoreDir() already exists.
+type Installer interface {
+	Install(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error)
+}
+
+// URLResolver can optionally be implemented by an Installer to resolve the source URL of
+// a tool without installing it. The URL is recorded in the lock file for tools installed
+// before they were added to it.
+type URLResolver interface {
+	ResolveURL(ctx context.Context, tool *Tool) (string, error)
+}
+
+type InstallerFunc func(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error)
+
+func (f InstallerFunc) Install(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+	return f(ctx, tool, storeDir)
+}
+
+type InstallResult struct {
+	// URL is the resolved location the tool was installed from.
+	URL string
+
+	// ArchiveSHA256 is the hex encoded SHA-256 digest of the downloaded archive, if any.
+	ArchiveSHA256 string
+}
+
+func (tf *ToolFetcher) RegisterInstaller(srcType recipes.SourceType, impl Installer) {
+	if tf.Installers == nil {
+		tf.Installers = map[recipes.SourceType]Installer{}
+	}
+
+	tf.Installers[srcType] = impl
+}
+
+func (tf *ToolFetcher) installerFor(srcType recipes.SourceType) (Installer, bool) {
+	if impl, ok := tf.Installers[srcType]; ok {
+		return impl, true
+	}
+
+	client := tf.fetchClient()
+	dl := installer.Downloader{Client: client, PartialDir: path.Join(tf.StoreDir, stagingDirName)}
+
+	switch srcType {
+	case recipes.SourceTypeGoInstall:
+		return goInstaller{client: client}, true
+	case recipes.SourceTypeBinDownload:
+		return binDownloadInstaller{dl: dl}, true
+	case recipes.SourceTypeGitHubRelease:
+		return gitHubReleaseInstaller{dl: dl}, true
+	default:
+		return nil, false
+	}
+}
+
+type goInstaller struct {
+	client *fetch.Client
+}
+
+func (i goInstaller) Install(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+	if url, err := i.ResolveURL(ctx, tool); err == nil {
+		tool.observe().OnResolve(tool, url)
+	}
+
+	result, err := installer.InstallWithGoInstall(ctx, tool.Recipe, tool.Version, path.Join(storeDir, tool.StoreDir()), tool.stdout(), tool.stderr())
+	return InstallResult(result), err
+}
+
+func (i goInstaller) ResolveURL(ctx context.Context, tool *Tool) (string, error) {
+	return installer.ResolveURL(ctx, i.client, tool.Recipe, tool.Version)
+}
+
+type binDownloadInstaller struct {
+	dl installer.Downloader
+}
+
+func (i binDownloadInstaller) Install(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+	result, err := installer.InstallFromBinDownload(ctx, observedDownloader(i.dl, tool), tool.Recipe, tool.Version, path.Join(storeDir, tool.StoreDir()))
+	return InstallResult(result), err
+}
+
+func (i binDownloadInstaller) ResolveURL(ctx context.Context, tool *Tool) (string, error) {
+	return installer.ResolveURL(ctx, i.dl.Client, tool.Recipe, tool.Version)
+}
+
+type gitHubReleaseInstaller struct {
+	dl installer.Downloader
+}
+
+func (i gitHubReleaseInstaller) Install(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+	result, err := installer.InstallFromGitHubRelease(ctx, observedDownloader(i.dl, tool), tool.Recipe, tool.Version, path.Join(storeDir, tool.StoreDir()))
+	return InstallResult(result), err
+}
+
+func (i gitHubReleaseInstaller) ResolveURL(ctx context.Context, tool *Tool) (string, error) {
+	return installer.ResolveURL(ctx, i.dl.Client, tool.Recipe, tool.Version)
+}
@@ -0,0 +1,159 @@
+package toolfetcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_RegisterInstaller(t *testing.T) {
+	cwd := t.TempDir()
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: npm://tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Recipes: []recipes.Recipe{{
+			Name: "tool",
+			Src:  recipes.Source{Type: "npm", URLTemplate: "tool"},
+			Test: []string{"--version"},
+		}},
+	}
+
+	var installs atomic.Int32
+	fetcher.RegisterInstaller("npm", scriptInstaller("#!/bin/sh\necho 1.0.0\n", &installs))
+
+	err = fetcher.FetchAll(context.Background())
+	require.NoError(t, err)
+
+	err = fetcher.FetchAll(context.Background())
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, installs.Load())
+	assert.FileExists(t, path.Join(cwd, ".bin", "tool"))
+}
+
+func TestToolFetcher_UnknownInstaller(t *testing.T) {
+	cwd := t.TempDir()
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: npm://tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Recipes:     []recipes.Recipe{{Name: "tool", Src: recipes.Source{Type: "npm"}}},
+	}
+
+	err = fetcher.Fetch(context.Background(), "tool")
+	assert.ErrorContains(t, err, "unknown install method npm")
+}
+
+func TestToolFetcher_StagedInstall(t *testing.T) {
+	tt := []struct {
+		name    string
+		script  string
+		failErr error
+		err     string
+	}{
+		{name: "Success", script: "#!/bin/sh\necho 1.0.0\n"},
+		{name: "Install Error", script: "#!/bin/sh\n", failErr: errors.New("interrupted"), err: "interrupted"},
+		{name: "Test Error", script: "#!/bin/sh\nexit 1\n", err: "error running tool --version"},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			cwd := t.TempDir()
+			storeDir := path.Join(cwd, ".bin", ".store")
+
+			toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+			err := os.WriteFile(toolfilePath, []byte("tool: npm://tool@1.0.0\n"), 0o644)
+			require.NoError(t, err)
+
+			fetcher := ToolFetcher{
+				VersionFile: toolfilePath,
+				BinDir:      path.Join(cwd, ".bin"),
+				Recipes: []recipes.Recipe{{
+					Name: "tool",
+					Src:  recipes.Source{Type: "npm", BinPath: "bin/tool"},
+					Test: []string{"--version"},
+				}},
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+
+			install := scriptInstaller(tt.script, nil)
+			fetcher.RegisterInstaller("npm", InstallerFunc(func(ctx context.Context, tool *Tool, dir string) (InstallResult, error) {
+				assert.NotEqual(t, storeDir, dir, "installers must not write into the store directly")
+
+				result, err := install(ctx, tool, dir)
+				require.NoError(t, err)
+
+				return result, tt.failErr
+			}))
+
+			err = fetcher.Fetch(context.Background(), "tool")
+
+			staged, _ := os.ReadDir(path.Join(storeDir, stagingDirName))
+			assert.Empty(t, staged)
+
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				assert.NoDirExists(t, path.Join(storeDir, "tool"))
+				assert.NoFileExists(t, path.Join(cwd, ".bin", "tool"))
+				return
+			}
+
+			require.NoError(t, err)
+			tool := storedTool(t, &fetcher, &Tool{Name: "tool", Version: "1.0.0", Recipe: &fetcher.Recipes[0]})
+			assert.FileExists(t, path.Join(storeDir, tool.BinPath()))
+			assert.FileExists(t, path.Join(cwd, ".bin", "tool"))
+		})
+	}
+}
+
+// scriptInstaller returns an installer writing script as the binary of every tool it installs,
+// counting the installs in installs if it is not nil.
+func scriptInstaller(script string, installs *atomic.Int32) InstallerFunc {
+	return func(_ context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+		if installs != nil {
+			installs.Add(1)
+		}
+
+		binPath := path.Join(storeDir, tool.BinPath())
+
+		err := os.MkdirAll(path.Dir(binPath), 0o755)
+		if err != nil {
+			return InstallResult{}, err
+		}
+
+		err = os.WriteFile(binPath, []byte(script), 0o755)
+
+		return InstallResult{URL: "script://" + tool.VersionedName()}, err
+	}
+}
+
+// storedTool returns tool with the binary digest fetcher locked it with, which is part of its store entry.
+func storedTool(t *testing.T, fetcher *ToolFetcher, tool *Tool) *Tool {
+	t.Helper()
+
+	lock, err := fetcher.readLockFile()
+	require.NoError(t, err)
+
+	stored := *tool
+	stored.binarySHA256 = lock.lockedBinary(tool)
+
+	return &stored
+}
@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how requests failing with a transient error are retried:
+// connection errors, 5xx responses, 408 Request Timeout and 429 Too Many Requests.
+// Other responses, like 404 Not Found, fail immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one. 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry, it doubles with every further retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the exponential backoff and waits requested by the server with a Retry-After header,
+	// which otherwise take precedence over the backoff. Values below 1 use the MaxBackoff of [DefaultRetryPolicy],
+	// waits are never uncapped.
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Client sends requests using HTTPClient and retries transient failures according to Retry.
+// The zero value uses http.DefaultClient and DefaultRetryPolicy.
+type Client struct {
+	HTTPClient *http.Client
+	Retry      RetryPolicy
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c != nil && c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return http.DefaultClient
+}
+
+func (c *Client) retryPolicy() RetryPolicy {
+	if c == nil || c.Retry.MaxAttempts < 1 {
+		return DefaultRetryPolicy
+	}
+
+	policy := c.Retry
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+
+	return policy
+}
+
+// Get requests url, retrying transient failures. Responses with other status codes than 200 are
+// returned as is, so that callers can handle e.g. 404 Not Found.
+func (c *Client) Get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	policy := c.retryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating new request for URL '%s': %w", url, err)
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		res, err := c.httpClient().Do(req)
+		if ctx.Err() != nil {
+			if err == nil {
+				res.Body.Close()
+			}
+
+			return nil, ctx.Err()
+		}
+
+		if attempt >= policy.MaxAttempts || (err == nil && !isRetryableStatus(res.StatusCode)) {
+			return res, err
+		}
+
+		wait := policy.backoff(attempt)
+		if err == nil {
+			// capped, so that a server can't stall the install for hours
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				wait = min(retryAfter, policy.MaxBackoff)
+			}
+
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+
+		err = sleep(ctx, wait)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the wait before retrying the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, p.MaxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func isRetryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// statusError describes a response with an unexpected status code.
+func statusError(url string, res *http.Response) error {
+	return fmt.Errorf("error fetching resource from '%s': %v %v", url, res.StatusCode, res.Status)
+}
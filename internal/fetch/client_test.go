@@ -0,0 +1,162 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Get(t *testing.T) {
+	tt := []struct {
+		name     string
+		statuses []int
+		header   http.Header
+		status   int
+		attempts int32
+	}{
+		{name: "Success", statuses: []int{200}, status: 200, attempts: 1},
+		{name: "Retry 5xx", statuses: []int{503, 502, 200}, status: 200, attempts: 3},
+		{name: "Retry Too Many Requests", statuses: []int{429, 200}, header: http.Header{"Retry-After": {"0"}}, status: 200, attempts: 2},
+		{name: "Retry After Capped", statuses: []int{503, 200}, header: http.Header{"Retry-After": {"86400"}}, status: 200, attempts: 2},
+		{name: "Not Found Fails Fast", statuses: []int{404, 200}, status: 404, attempts: 1},
+		{name: "Gives Up", statuses: []int{500, 500, 500, 500, 200}, status: 500, attempts: 3},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				status := tt.statuses[attempts.Add(1)-1]
+				for key, values := range tt.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(http.StatusText(status)))
+			}))
+			t.Cleanup(srv.Close)
+
+			client := &Client{Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+
+			res, err := client.Get(context.Background(), srv.URL, nil)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Equal(t, http.StatusText(tt.status), string(body))
+			assert.Equal(t, tt.attempts, attempts.Load())
+		})
+	}
+}
+
+func TestClient_Get_ConnectionReset(t *testing.T) {
+	var attempts atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &Client{Retry: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+
+	res, err := client.Get(context.Background(), srv.URL, nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestClient_Get_Header(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := (&Client{}).Get(context.Background(), srv.URL, http.Header{"Authorization": {"Bearer token"}})
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", string(body))
+}
+
+func TestClient_RetryPolicy_Backoff(t *testing.T) {
+	tt := []struct {
+		name     string
+		policy   RetryPolicy
+		expected []time.Duration
+	}{
+		{
+			name:     "Capped",
+			policy:   RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second},
+			expected: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:     "Default Max Backoff",
+			policy:   RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second},
+		},
+		{
+			name:     "Zero Value",
+			expected: []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := (&Client{Retry: tt.policy}).retryPolicy()
+
+			var actual []time.Duration
+			for attempt := 1; attempt <= len(tt.expected); attempt++ {
+				actual = append(actual, policy.backoff(attempt))
+			}
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "Empty"},
+		{name: "Seconds", value: "3", expected: 3 * time.Second, ok: true},
+		{name: "Negative Seconds", value: "-3", expected: 0, ok: true},
+		{name: "HTTP Date", value: "Mon, 01 Jan 2024 12:00:30 GMT", expected: 30 * time.Second, ok: true},
+		{name: "HTTP Date In The Past", value: "Mon, 01 Jan 2024 11:00:00 GMT", expected: 0, ok: true},
+		{name: "Invalid", value: "soon"},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
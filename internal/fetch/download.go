@@ -0,0 +1,237 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// partialMeta is stored next to a partial download. It identifies the version of the file that was
+// partially downloaded, so that a resumed download only appends the rest of the same version.
+type partialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ifRange returns the value of the If-Range header for resuming the download. Weak ETags can't be used for If-Range.
+func (m partialMeta) ifRange() (string, bool) {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag, true
+	}
+
+	if m.LastModified != "" {
+		return m.LastModified, true
+	}
+
+	return "", false
+}
+
+// partialPath is the file in dir in which url is downloaded.
+func partialPath(dir string, url string) string {
+	digest := sha256.Sum256([]byte(url))
+	return path.Join(dir, hex.EncodeToString(digest[:8])+".part")
+}
+
+func partialMetaPath(filePath string) string {
+	return filePath + ".json"
+}
+
+// removePartial removes a partial download and its metadata.
+func removePartial(filePath string) error {
+	return errors.Join(removeIfExists(filePath), removeIfExists(partialMetaPath(filePath)))
+}
+
+func removeIfExists(filePath string) error {
+	err := os.Remove(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// downloadTo downloads url to filePath. Interrupted downloads are retried according to the client's retry policy.
+// With opts.PartialDir set, a retry, or a later call for the same url and filePath, only requests the missing part of the file
+// using a range request, as long as the server still serves the same version of the file.
+// The returned response's body is already closed.
+func downloadTo(ctx context.Context, url string, filePath string, opts Options) (*http.Response, error) {
+	policy := opts.Client.retryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		res, err := downloadAttempt(ctx, url, filePath, opts)
+		if err == nil || !errors.Is(err, errDownloadInterrupted) || attempt >= policy.MaxAttempts {
+			return res, err
+		}
+
+		if sleep(ctx, policy.backoff(attempt)) != nil {
+			return nil, err
+		}
+	}
+}
+
+func downloadAttempt(ctx context.Context, url string, filePath string, opts Options) (*http.Response, error) {
+	resume := opts.PartialDir != ""
+	var offset int64
+	header := http.Header{}
+
+	if resume {
+		if meta, size, ok := readPartial(url, filePath); ok {
+			if ifRange, ok := meta.ifRange(); ok {
+				offset = size
+				header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+				header.Set("If-Range", ifRange)
+			}
+		}
+	}
+
+	res, err := opts.Client.Get(ctx, url, header)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching resource from '%s': %w", url, err)
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		// the server sent the whole file, either no range was requested or the file changed
+		flags |= os.O_TRUNC
+		offset = 0
+
+		if resume {
+			err = writePartialMeta(filePath, partialMeta{URL: url, ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")})
+			if err != nil {
+				return nil, err
+			}
+		}
+	case offset > 0 && res.StatusCode == http.StatusPartialContent && contentRangeStart(res) == offset:
+		flags |= os.O_APPEND
+	case offset > 0 && (res.StatusCode == http.StatusPartialContent || res.StatusCode == http.StatusRequestedRangeNotSatisfiable):
+		// the server doesn't agree with the partial download, start over
+		err = removePartial(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return downloadAttempt(ctx, url, filePath, opts)
+	default:
+		return nil, statusError(url, res)
+	}
+
+	f, err := os.OpenFile(filePath, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening download file %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	var body io.Reader = res.Body
+	if opts.OnProgress != nil {
+		total := int64(-1)
+		if res.ContentLength >= 0 {
+			total = offset + res.ContentLength
+		}
+
+		body = &progressReader{r: res.Body, downloaded: offset, total: total, onProgress: opts.OnProgress}
+		opts.OnProgress(offset, total)
+	}
+
+	_, err = io.Copy(f, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", errDownloadInterrupted, url, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error writing download file %s: %w", filePath, err)
+	}
+
+	return res, nil
+}
+
+// readPartial returns the metadata and size of a partial download of url, if there is one.
+func readPartial(url string, filePath string) (partialMeta, int64, bool) {
+	data, err := os.ReadFile(partialMetaPath(filePath))
+	if err != nil {
+		return partialMeta{}, 0, false
+	}
+
+	var meta partialMeta
+	if json.Unmarshal(data, &meta) != nil || meta.URL != url {
+		return partialMeta{}, 0, false
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() == 0 {
+		return partialMeta{}, 0, false
+	}
+
+	return meta, info.Size(), true
+}
+
+func writePartialMeta(filePath string, meta partialMeta) error {
+	if _, ok := meta.ifRange(); !ok {
+		// without a validator the download can't be resumed safely
+		return removeIfExists(partialMetaPath(filePath))
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("error encoding download metadata: %w", err)
+	}
+
+	err = os.WriteFile(partialMetaPath(filePath), data, 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing download metadata %s: %w", partialMetaPath(filePath), err)
+	}
+
+	return nil
+}
+
+// contentRangeStart returns the first byte position of the Content-Range header, e.g. 100 for "bytes 100-199/200",
+// or -1 if it is missing or invalid.
+func contentRangeStart(res *http.Response) int64 {
+	rest, ok := strings.CutPrefix(res.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+
+	first, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return -1
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return start
+}
+
+// progressReader reports the bytes read from r, starting at downloaded.
+type progressReader struct {
+	r          io.Reader
+	downloaded int64
+	total      int64
+	onProgress func(downloaded int64, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.downloaded += int64(n)
+		p.onProgress(p.downloaded, p.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,201 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadAndUnpackTo_Resume(t *testing.T) {
+	archive := newTarGz(t, map[string]string{"tool": randomString(t, 64<<10)})
+	digest := sha256.Sum256(archive)
+	half := len(archive) / 2
+
+	tt := []struct {
+		name        string
+		partial     []byte
+		partialETag string
+		etag        string
+		noRanges    bool
+		interrupt   bool
+		ranges      []string
+	}{
+		{name: "No Partial", etag: `"v1"`, ranges: []string{""}},
+		{name: "Resume Partial", partial: archive[:half], partialETag: `"v1"`, etag: `"v1"`, ranges: []string{"bytes=" + strconv.Itoa(half) + "-"}},
+		{name: "Changed File", partial: archive[:half], partialETag: `"v0"`, etag: `"v1"`, ranges: []string{"bytes=" + strconv.Itoa(half) + "-"}},
+		{name: "No Range Support", partial: archive[:half], partialETag: `"v1"`, etag: `"v1"`, noRanges: true, ranges: []string{"bytes=" + strconv.Itoa(half) + "-"}},
+		{name: "Weak ETag", partial: archive[:half], partialETag: `W/"v1"`, etag: `W/"v1"`, ranges: []string{""}},
+		{name: "Range Not Satisfiable", partial: append(archive[:len(archive):len(archive)], 0), partialETag: `"v1"`, etag: `"v1"`, ranges: []string{"bytes=" + strconv.Itoa(len(archive)+1) + "-", ""}},
+		{name: "Interrupted", etag: `"v1"`, interrupt: true, ranges: []string{"", "bytes=" + strconv.Itoa(half) + "-"}},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ranges []string
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				ranges = append(ranges, r.Header.Get("Range"))
+				first := len(ranges) == 1
+				mu.Unlock()
+
+				w.Header().Set("ETag", tt.etag)
+
+				if tt.interrupt && first {
+					serveInterrupted(w, archive, half)
+					return
+				}
+
+				if tt.noRanges {
+					_, _ = w.Write(archive)
+					return
+				}
+
+				http.ServeContent(w, r, "tool.tar.gz", time.Time{}, bytes.NewReader(archive))
+			}))
+			t.Cleanup(srv.Close)
+
+			partialDir := t.TempDir()
+			url := srv.URL + "/tool.tar.gz"
+
+			if tt.partial != nil {
+				writePartial(t, partialDir, url, tt.partial, partialMeta{URL: url, ETag: tt.partialETag})
+			}
+
+			destPath := path.Join(t.TempDir(), "tool_1.0.0")
+			client := &Client{Retry: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+
+			result, err := DownloadAndUnpackTo(context.Background(), url, destPath, Options{
+				SHA256:     hex.EncodeToString(digest[:]),
+				Client:     client,
+				PartialDir: partialDir,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, hex.EncodeToString(digest[:]), result.SHA256)
+			assert.FileExists(t, path.Join(destPath, "tool"))
+			assert.Equal(t, tt.ranges, ranges)
+			assert.NoFileExists(t, partialPath(partialDir, url))
+			assert.NoFileExists(t, partialMetaPath(partialPath(partialDir, url)))
+		})
+	}
+}
+
+func TestDownloadAndUnpackTo_KeepsPartial(t *testing.T) {
+	archive := newTarGz(t, map[string]string{"tool": randomString(t, 64<<10)})
+	half := len(archive) / 2
+
+	var mu sync.Mutex
+	var ranges []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		w.Header().Set("Last-Modified", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+
+		if first {
+			serveInterrupted(w, archive, half)
+			return
+		}
+
+		http.ServeContent(w, r, "tool.tar.gz", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), bytes.NewReader(archive))
+	}))
+	t.Cleanup(srv.Close)
+
+	partialDir := t.TempDir()
+	url := srv.URL + "/tool.tar.gz"
+	opts := Options{
+		Client:     &Client{Retry: RetryPolicy{MaxAttempts: 1}},
+		PartialDir: partialDir,
+	}
+
+	_, err := DownloadAndUnpackTo(context.Background(), url, path.Join(t.TempDir(), "tool_1.0.0"), opts)
+	require.ErrorIs(t, err, errDownloadInterrupted)
+
+	partial, err := os.ReadFile(partialPath(partialDir, url))
+	require.NoError(t, err)
+	assert.Equal(t, archive[:half], partial)
+
+	var progress [][2]int64
+	opts.OnProgress = func(downloaded int64, total int64) {
+		progress = append(progress, [2]int64{downloaded, total})
+	}
+
+	destPath := path.Join(t.TempDir(), "tool_1.0.0")
+
+	_, err = DownloadAndUnpackTo(context.Background(), url, destPath, opts)
+	require.NoError(t, err)
+
+	assert.FileExists(t, path.Join(destPath, "tool"))
+	require.NotEmpty(t, progress)
+	assert.Equal(t, [2]int64{int64(half), int64(len(archive))}, progress[0])
+	assert.Equal(t, [2]int64{int64(len(archive)), int64(len(archive))}, progress[len(progress)-1])
+	assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(half) + "-"}, ranges)
+	assert.NoFileExists(t, partialPath(partialDir, url))
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tt := []struct {
+		value    string
+		expected int64
+	}{
+		{value: "bytes 100-199/200", expected: 100},
+		{value: "bytes 0-0/*", expected: 0},
+		{value: "bytes */200", expected: -1},
+		{value: "items 1-2/3", expected: -1},
+		{value: "", expected: -1},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.value, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{"Content-Range": {tt.value}}}
+			assert.Equal(t, tt.expected, contentRangeStart(res))
+		})
+	}
+}
+
+// serveInterrupted announces the full content, but closes the connection after n bytes.
+func serveInterrupted(w http.ResponseWriter, content []byte, n int) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content[:n])
+
+	if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+func writePartial(t *testing.T, dir string, url string, content []byte, meta partialMeta) {
+	t.Helper()
+
+	filePath := partialPath(dir, url)
+	require.NoError(t, os.WriteFile(filePath, content, 0o644))
+	require.NoError(t, writePartialMeta(filePath, meta))
+}
+
+func randomString(t *testing.T, n int) string {
+	t.Helper()
+
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	return string(b)
+}
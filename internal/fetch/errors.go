@@ -0,0 +1,21 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+var ErrReleaseNotFound = errors.New("release not found")
+var ErrUnknownArchiveFormat = errors.New("unknown archive format")
+
+var errDownloadInterrupted = errors.New("download interrupted")
+
+type UnsafeEntryError struct {
+	Entry  string
+	Reason string
+}
+
+func (e *UnsafeEntryError) Error() string {
+	return fmt.Sprintf("unsafe archive entry '%s': %s", e.Entry, e.Reason)
+}
@@ -1,208 +1,135 @@
 package fetch
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"compress/gzip"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path"
+	"strings"
+
+	"github.com/RobinThrift/toolfetcher/internal/fs"
 )
 
-func DownloadAndUnpackTo(ctx context.Context, url string, destPath string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return fmt.Errorf("error creating new request for URL '%s': %w", url, err)
-	}
+const maxDownloadSize = 10 << 20
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error fetching resource from '%s': %w", url, err)
-	}
-	defer res.Body.Close()
+type Options struct {
+	// SHA256 is the expected hex encoded SHA-256 digest of the downloaded file.
+	// The download is not verified if it is empty.
+	SHA256 string
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("error fetching resource from '%s': %v %v", url, res.StatusCode, res.Status)
-	}
+	// Format overrides the archive format detection.
+	Format ArchiveFormat
 
-	ext := path.Ext(url)
-	tempFilePattern := path.Base(destPath) + "-*" + ext
+	// BinName is the filename for raw or gzip compressed single binary downloads.
+	BinName string
 
-	tmpFile, err := os.CreateTemp("", tempFilePattern)
-	if err != nil {
-		return fmt.Errorf("error creating temporary file")
-	}
-	defer func() {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-	}()
+	// StripComponents removes leading directories from archive entries.
+	StripComponents int
 
-	_, err = io.Copy(tmpFile, res.Body)
-	if err != nil {
-		return fmt.Errorf("error downloading file: %w", err)
-	}
+	// Include limits extraction to archive entries matching one of the glob patterns.
+	Include []string
 
-	err = unpackArchive(tmpFile.Name(), destPath, ext)
-	if err != nil {
-		return err
-	}
+	// Client is used to download the file, nil uses the defaults of [Client].
+	Client *Client
 
-	return nil
-}
+	// PartialDir keeps interrupted downloads, so that they can be resumed by the next
+	// call with the same URL. Without it, downloads are made to a temporary file.
+	PartialDir string
 
-func unpackArchive(filePath string, destPath string, ext string) error {
-	switch ext {
-	case ".zip":
-		return unpackZipArchive(filePath, destPath)
-	case ".tar":
-		return unpackTarArchive(filePath, destPath)
-	case ".gz":
-		return unpackTarGzipArchive(filePath, destPath)
-	case ".xz":
-		return unpackTarXZArchive(filePath, destPath)
-	}
+	// OnProgress is called while downloading with the number of bytes downloaded so far
+	// and the total size, which is -1 if unknown.
+	OnProgress func(downloaded int64, total int64)
 
-	return fmt.Errorf("unknown archive %s", ext)
+	// OnExtract is called once the download is complete, before it is unpacked.
+	OnExtract func()
 }
 
-func unpackZipArchive(filePath string, destPath string) error {
-	archive, err := zip.OpenReader(filePath)
-	if err != nil {
-		return fmt.Errorf("error constructing new ZIP reader: %w", err)
-	}
+type Result struct {
+	// SHA256 is the hex encoded SHA-256 digest of the downloaded file.
+	SHA256 string
+}
 
-	err = os.MkdirAll(destPath, 0o755)
-	if err != nil {
-		return fmt.Errorf("error creating directory %s: %w", destPath, err)
-	}
+func DownloadAndUnpackTo(ctx context.Context, url string, destPath string, opts Options) (Result, error) {
+	filePath, resume := "", opts.PartialDir != ""
 
-	for _, compressed := range archive.File {
-		f, err := compressed.Open()
+	if resume {
+		filePath = partialPath(opts.PartialDir, url)
+
+		lock, err := fs.Lock(ctx, filePath+".lock")
 		if err != nil {
-			return fmt.Errorf("error openening compressed file %s: %w", compressed.Name, err)
+			return Result{}, err
 		}
-		defer f.Close()
-
-		destFilePath := path.Join(destPath, compressed.Name)
-
-		err = os.MkdirAll(path.Dir(destFilePath), 0o755)
+		defer lock.Unlock()
+	} else {
+		tmpFile, err := os.CreateTemp("", path.Base(destPath)+"-*")
 		if err != nil {
-			return fmt.Errorf("error creating directory %s: %w", path.Dir(destFilePath), err)
+			return Result{}, fmt.Errorf("error creating temporary file: %w", err)
 		}
 
-		if compressed.Mode().IsDir() {
-			err = os.Mkdir(destFilePath, 0o755)
-			if err != nil {
-				return fmt.Errorf("error creating directory %s: %w", destFilePath, err)
-			}
-		} else {
-			destFile, err := os.OpenFile(destFilePath, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, compressed.Mode())
-			if err != nil {
-				return fmt.Errorf("error creating file %s: %w", destFilePath, err)
-			}
-			defer destFile.Close()
-
-			_, err = io.Copy(destFile, f)
-			if err != nil {
-				return fmt.Errorf("error decompressing file %s to %s: %w", compressed.Name, destFilePath, err)
-			}
-		}
+		tmpFile.Close()
+		filePath = tmpFile.Name()
 	}
 
-	return nil
-}
-
-func unpackTarGzipArchive(filePath string, destPath string) error {
-	r, err := os.Open(filePath)
+	res, err := downloadTo(ctx, url, filePath, opts)
 	if err != nil {
-		return err
-	}
-	defer r.Close()
+		if !resume {
+			os.Remove(filePath)
+		}
 
-	zr, err := gzip.NewReader(r)
-	if err != nil {
-		return fmt.Errorf("error constructing new gzip reader: %w", err)
+		return Result{}, err
 	}
-	defer zr.Close()
 
-	return unpackTarReader(zr, destPath)
-}
+	// the download is complete, it is not kept whether it can be unpacked or not
+	defer removePartial(filePath)
 
-func unpackTarArchive(filePath string, destPath string) error {
-	r, err := os.Open(filePath)
+	// the digest is calculated from the file, as a resumed download was only partially received by this call
+	digest, err := fs.FileSHA256(filePath)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
-	defer r.Close()
-
-	return unpackTarReader(r, destPath)
-}
 
-func unpackTarReader(r io.Reader, destPath string) error {
-	archive := tar.NewReader(r)
-
-	err := os.MkdirAll(destPath, 0o755)
-	if err != nil {
-		return fmt.Errorf("error creating directory %s: %w", destPath, err)
+	if opts.SHA256 != "" && !strings.EqualFold(digest, opts.SHA256) {
+		return Result{}, fmt.Errorf("%w for '%s': expected %s, got %s", ErrChecksumMismatch, url, opts.SHA256, digest)
 	}
 
-	for {
-		header, err := archive.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
+	format := opts.Format
+	if format == "" {
+		var ok bool
+		format, ok = detectArchiveFormat(filePath, res)
+		if !ok {
+			return Result{}, fmt.Errorf("%w: '%s'", ErrUnknownArchiveFormat, url)
 		}
+	}
 
-		destFilePath := path.Join(destPath, header.FileInfo().Name())
-		if header.FileInfo().IsDir() {
-			err = os.MkdirAll(destFilePath, 0o755)
-			if err != nil {
-				return fmt.Errorf("error creating directory %s: %w", destFilePath, err)
-			}
-		} else {
-			destFile, err := os.OpenFile(destFilePath, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, header.FileInfo().Mode())
-			if err != nil {
-				return fmt.Errorf("error creating file %s: %w", destFilePath, err)
-			}
-			defer destFile.Close()
-
-			_, err = io.Copy(destFile, archive)
-			if err != nil {
-				return fmt.Errorf("error decompressing file %s to %s: %w", header.Name, destFilePath, err)
-			}
-		}
+	if opts.OnExtract != nil {
+		opts.OnExtract()
+	}
+
+	err = unpackArchive(filePath, destPath, format, opts)
+	if err != nil {
+		return Result{}, err
 	}
 
-	return nil
+	return Result{SHA256: digest}, nil
 }
 
-func unpackTarXZArchive(filePath string, destPath string) error {
-	tmpdir, err := os.MkdirTemp("", path.Base(destPath))
+func Download(ctx context.Context, client *Client, url string) ([]byte, error) {
+	res, err := client.Get(ctx, url, nil)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error fetching resource from '%s': %w", url, err)
 	}
-	defer func() {
-		_ = os.Remove(tmpdir) // ignore errors
-	}()
+	defer res.Body.Close()
 
-	cmd := exec.Command("tar", "--strip-components=1", "-C", tmpdir, "-xf", filePath)
-	err = cmd.Run()
-	if err != nil {
-		var exitErr *exec.ExitError
-		var output string
-		if errors.As(err, &exitErr) {
-			output = "\n" + string(exitErr.Stderr)
-		}
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(url, res)
+	}
 
-		return fmt.Errorf("error unpacking .xz archive using the 'tar' command: %w%s", err, output)
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxDownloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body from '%s': %w", url, err)
 	}
 
-	return os.Rename(tmpdir, destPath)
+	return body, nil
 }
@@ -0,0 +1,214 @@
+package fetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadAndUnpackTo_Checksum(t *testing.T) {
+	archive := newTarGz(t, map[string]string{"tool": "#!/bin/sh\necho tool\n"})
+	digest := sha256.Sum256(archive)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	tt := []struct {
+		name   string
+		sha256 string
+		err    error
+	}{
+		{name: "No Checksum"},
+		{name: "Matching Checksum", sha256: hex.EncodeToString(digest[:])},
+		{name: "Mismatched Checksum", sha256: hex.EncodeToString(make([]byte, sha256.Size)), err: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := path.Join(t.TempDir(), "tool_1.0.0")
+
+			result, err := DownloadAndUnpackTo(context.Background(), srv.URL+"/tool.tar.gz", destPath, Options{SHA256: tt.sha256})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.NoDirExists(t, destPath)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(digest[:]), result.SHA256)
+			assert.FileExists(t, path.Join(destPath, "tool"))
+		})
+	}
+}
+
+func TestDownloadAndUnpackTo_FormatDetection(t *testing.T) {
+	archive := newTarGz(t, map[string]string{"tool": "tool"})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/cdn/blob", http.StatusFound)
+			return
+		case "/disposition":
+			w.Header().Set("Content-Disposition", `attachment; filename="tool.tar.gz"`)
+		}
+
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	tt := []struct {
+		name    string
+		url     string
+		format  ArchiveFormat
+		wantErr bool
+	}{
+		{name: "tgz", url: "/tool.tgz"},
+		{name: "Query Parameters", url: "/tool.tar.gz?raw=1"},
+		{name: "No Extension", url: "/download"},
+		{name: "Redirect", url: "/redirect"},
+		{name: "Content-Disposition", url: "/disposition"},
+		{name: "Wrong Extension", url: "/tool.zip"},
+		{name: "Explicit Format", url: "/download", format: ArchiveFormatTarGz},
+		{name: "Wrong Explicit Format", url: "/download", format: ArchiveFormatZip, wantErr: true},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := path.Join(t.TempDir(), "tool_1.0.0")
+
+			_, err := DownloadAndUnpackTo(context.Background(), srv.URL+tt.url, destPath, Options{Format: tt.format})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.FileExists(t, path.Join(destPath, "tool"))
+		})
+	}
+}
+
+func TestDownloadAndUnpackTo_SingleBinary(t *testing.T) {
+	script := []byte("#!/bin/sh\necho tool\n")
+	elf := append([]byte("\x7fELF"), make([]byte, 64)...)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, err := gw.Write(script)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	tarGz := newTarGz(t, map[string]string{"tool": string(script)})
+
+	files := map[string][]byte{
+		"/tool-linux-amd64":    elf,
+		"/tool.sh":             script,
+		"/tool-linux-amd64.gz": gzipped.Bytes(),
+		"/download":            gzipped.Bytes(),
+		"/tool.tar.gz":         tarGz,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(files[r.URL.Path])
+	}))
+	t.Cleanup(srv.Close)
+
+	tt := []struct {
+		name     string
+		url      string
+		format   ArchiveFormat
+		expected []byte
+	}{
+		{name: "ELF", url: "/tool-linux-amd64", expected: elf},
+		{name: "Shebang", url: "/tool.sh", expected: script},
+		{name: "Explicit Binary", url: "/tool.sh", format: ArchiveFormatBinary, expected: script},
+		{name: "Gzip", url: "/tool-linux-amd64.gz", expected: script},
+		{name: "Gzip No Extension", url: "/download", expected: script},
+		{name: "Tar Gzip", url: "/tool.tar.gz", expected: script},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := path.Join(t.TempDir(), "tool_1.0.0")
+
+			_, err := DownloadAndUnpackTo(context.Background(), srv.URL+tt.url, destPath, Options{Format: tt.format, BinName: "tool"})
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(path.Join(destPath, "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, content)
+
+			info, err := os.Stat(path.Join(destPath, "tool"))
+			require.NoError(t, err)
+			assert.NotZero(t, info.Mode().Perm()&0o100, "binary must be executable")
+		})
+	}
+
+	t.Run("Missing Binary Name", func(t *testing.T) {
+		_, err := DownloadAndUnpackTo(context.Background(), srv.URL+"/tool-linux-amd64", path.Join(t.TempDir(), "tool_1.0.0"), Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestDetectArchiveFormat_Fallbacks(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "download")
+	err := os.WriteFile(filePath, []byte("not an archive"), 0o644)
+	require.NoError(t, err)
+
+	reqURL, err := url.Parse("https://example.com/tool.tar.xz?raw=1")
+	require.NoError(t, err)
+
+	res := &http.Response{Header: http.Header{}, Request: &http.Request{URL: reqURL}}
+
+	format, ok := detectArchiveFormat(filePath, res)
+	assert.True(t, ok)
+	assert.Equal(t, ArchiveFormatTarXz, format)
+
+	res.Header.Set("Content-Disposition", `attachment; filename="tool.zip"`)
+
+	format, ok = detectArchiveFormat(filePath, res)
+	assert.True(t, ok)
+	assert.Equal(t, ArchiveFormatZip, format)
+
+	res.Header.Del("Content-Disposition")
+	res.Request.URL.Path = "/download"
+
+	_, ok = detectArchiveFormat(filePath, res)
+	assert.False(t, ok)
+}
+
+func newTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		require.NoError(t, err)
+
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip    ArchiveFormat = "zip"
+	ArchiveFormatTar    ArchiveFormat = "tar"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarXz  ArchiveFormat = "tar.xz"
+	ArchiveFormatTarBz2 ArchiveFormat = "tar.bz2"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+
+	// ArchiveFormatGz is a single gzip compressed file, not a tarball.
+	ArchiveFormatGz ArchiveFormat = "gz"
+
+	// ArchiveFormatBinary is an uncompressed executable.
+	ArchiveFormatBinary ArchiveFormat = "binary"
+)
+
+var formatMagic = []struct {
+	format ArchiveFormat
+	offset int
+	magic  []byte
+}{
+	{format: ArchiveFormatZip, magic: []byte("PK\x03\x04")},
+	{format: ArchiveFormatZip, magic: []byte("PK\x05\x06")},
+	{format: ArchiveFormatTarGz, magic: []byte{0x1f, 0x8b}},
+	{format: ArchiveFormatTarXz, magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{format: ArchiveFormatTarBz2, magic: []byte("BZh")},
+	{format: ArchiveFormatTarZst, magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{format: ArchiveFormatTar, offset: 257, magic: []byte("ustar")},
+}
+
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32-bit
+	{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit, little endian
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit, little endian
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal
+	[]byte("MZ"),             // PE
+	[]byte("#!"),
+}
+
+var formatExts = []struct {
+	format ArchiveFormat
+	exts   []string
+}{
+	{format: ArchiveFormatZip, exts: []string{".zip"}},
+	{format: ArchiveFormatTarGz, exts: []string{".tar.gz", ".tgz"}},
+	{format: ArchiveFormatGz, exts: []string{".gz"}},
+	{format: ArchiveFormatTarXz, exts: []string{".tar.xz", ".txz", ".xz"}},
+	{format: ArchiveFormatTarBz2, exts: []string{".tar.bz2", ".tbz2", ".tbz", ".bz2"}},
+	{format: ArchiveFormatTarZst, exts: []string{".tar.zst", ".tzst", ".zst"}},
+	{format: ArchiveFormatTar, exts: []string{".tar"}},
+	{format: ArchiveFormatBinary, exts: []string{".exe"}},
+}
+
+// detectArchiveFormat determines the format of the downloaded file at filePath by its magic bytes,
+// falling back to the filename in the Content-Disposition header and then the extension of the
+// final (post redirect) URL.
+func detectArchiveFormat(filePath string, res *http.Response) (ArchiveFormat, bool) {
+	if format, ok := sniffArchiveFormat(filePath); ok {
+		return format, true
+	}
+
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil {
+		if format, ok := archiveFormatFromFilename(params["filename"]); ok {
+			return format, true
+		}
+	}
+
+	if res.Request != nil && res.Request.URL != nil {
+		return archiveFormatFromFilename(res.Request.URL.Path)
+	}
+
+	return "", false
+}
+
+func sniffArchiveFormat(filePath string) (ArchiveFormat, bool) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", false
+	}
+
+	header = header[:n]
+
+	for _, m := range formatMagic {
+		if len(header) >= m.offset+len(m.magic) && bytes.Equal(header[m.offset:m.offset+len(m.magic)], m.magic) {
+			if m.format == ArchiveFormatTarGz && !isGzippedTar(f) {
+				return ArchiveFormatGz, true
+			}
+
+			return m.format, true
+		}
+	}
+
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(header, magic) {
+			return ArchiveFormatBinary, true
+		}
+	}
+
+	return "", false
+}
+
+func isGzippedTar(f *os.File) bool {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return false
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	defer zr.Close()
+
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(zr, header)
+
+	return n >= 262 && bytes.Equal(header[257:262], []byte("ustar"))
+}
+
+func archiveFormatFromFilename(filename string) (ArchiveFormat, bool) {
+	filename = strings.ToLower(path.Base(filename))
+
+	for _, f := range formatExts {
+		for _, ext := range f.exts {
+			if strings.HasSuffix(filename, ext) {
+				return f.format, true
+			}
+		}
+	}
+
+	return "", false
+}
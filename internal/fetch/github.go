@@ -100,3 +100,44 @@ func DownloadLicenseFromGithub(ctx context.Context, repoURL string, ref string)
 
 	return &ghlicense, nil
 }
+
+type GitHubReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func ListGitHubReleaseAssets(ctx context.Context, client *Client, repo string, tag string) ([]GitHubReleaseAsset, error) {
+	url := "https://api.github.com/repos/" + repo + "/releases/tags/" + tag
+
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+
+	if tokenFromEnv := os.Getenv("GITHUB_TOKEN"); tokenFromEnv != "" {
+		header.Set("Authorization", "Bearer "+tokenFromEnv)
+	}
+
+	res, err := client.Get(ctx, url, header)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching release '%s' of '%s' from GitHub: %w", tag, repo, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: '%s' of '%s'", ErrReleaseNotFound, tag, repo)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching release '%s' of '%s' from GitHub: %v %v", tag, repo, res.StatusCode, res.Status)
+	}
+
+	var release struct {
+		Assets []GitHubReleaseAsset `json:"assets"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&release)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GitHub response JSON (url: '%s'): %w", url, err)
+	}
+
+	return release.Assets, nil
+}
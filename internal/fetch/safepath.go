@@ -0,0 +1,110 @@
+package fetch
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// safeJoin joins an archive entry name onto destPath, rejecting names that would resolve outside of destPath.
+func safeJoin(destPath string, name string) (string, error) {
+	normalized := strings.ReplaceAll(name, `\`, "/")
+
+	if path.IsAbs(normalized) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" || hasDriveLetter(normalized) {
+		return "", &UnsafeEntryError{Entry: name, Reason: "absolute path"}
+	}
+
+	for _, component := range strings.Split(normalized, "/") {
+		if component == ".." {
+			return "", &UnsafeEntryError{Entry: name, Reason: "path traversal"}
+		}
+	}
+
+	return path.Join(destPath, normalized), nil
+}
+
+// checkSymlink rejects symlinks whose target, relative to the link's directory, lies outside of destPath.
+//
+// The check is lexical, which is only sound if the target doesn't step back out of a path component with "..":
+// the OS resolves "b/.." physically, so if b is (or later becomes) a symlink, the target ends up elsewhere.
+// With ".." only allowed as leading components, a target climbs up real directories (checkParents ensures
+// the link's parents aren't symlinks) and then descends into destPath, where every symlink it passes
+// through was itself confined by this check.
+func checkSymlink(destPath string, entryPath string, name string, linkname string) error {
+	normalized := strings.ReplaceAll(linkname, `\`, "/")
+
+	if path.IsAbs(normalized) || filepath.IsAbs(linkname) || hasDriveLetter(normalized) {
+		return &UnsafeEntryError{Entry: name, Reason: "symlink to absolute path " + linkname}
+	}
+
+	descended := false
+	for _, component := range strings.Split(normalized, "/") {
+		switch component {
+		case "", ".":
+		case "..":
+			if descended {
+				return &UnsafeEntryError{Entry: name, Reason: "symlink target " + linkname + " steps back out of a path component"}
+			}
+		default:
+			descended = true
+		}
+	}
+
+	destPath = path.Clean(destPath)
+
+	target := path.Join(path.Dir(entryPath), normalized)
+	if target != destPath && !strings.HasPrefix(target, destPath+"/") {
+		return &UnsafeEntryError{Entry: name, Reason: "symlink target " + linkname + " outside of destination"}
+	}
+
+	return nil
+}
+
+// checkHardlink rejects hardlinks whose target, relative to the archive root, lies outside of destPath.
+func checkHardlink(destPath string, name string, linkname string) error {
+	_, err := safeJoin(destPath, linkname)
+	if err != nil {
+		return &UnsafeEntryError{Entry: name, Reason: "hardlink target " + linkname + " outside of destination"}
+	}
+
+	return nil
+}
+
+// checkParents rejects entries that would be written through a symlink extracted earlier,
+// as the symlink checks only consider the link's own location.
+func checkParents(destPath string, entryPath string, name string) error {
+	destPath = path.Clean(destPath)
+
+	rel := strings.TrimPrefix(path.Dir(entryPath), destPath)
+
+	current := destPath
+	for _, component := range strings.Split(rel, "/") {
+		if component == "" {
+			continue
+		}
+
+		current = path.Join(current, component)
+
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return &UnsafeEntryError{Entry: name, Reason: "path through symlink " + strings.TrimPrefix(current, destPath+"/")}
+		}
+	}
+
+	return nil
+}
+
+func hasDriveLetter(name string) bool {
+	return len(name) >= 2 && name[1] == ':' && ((name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z'))
+}
@@ -0,0 +1,412 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func unpackArchive(filePath string, destPath string, format ArchiveFormat, opts Options) error {
+	switch format {
+	case ArchiveFormatBinary:
+		return unpackBinary(filePath, destPath, opts.BinName, nil)
+	case ArchiveFormatGz:
+		return unpackBinary(filePath, destPath, opts.BinName, gzipDecompressor)
+	case ArchiveFormatZip:
+		return unpackZipArchive(filePath, destPath, opts)
+	case ArchiveFormatTar:
+		return unpackTarArchive(filePath, destPath, opts)
+	case ArchiveFormatTarGz:
+		return unpackCompressedTarArchive(filePath, destPath, gzipDecompressor, opts)
+	case ArchiveFormatTarXz:
+		return unpackCompressedTarArchive(filePath, destPath, xzDecompressor, opts)
+	case ArchiveFormatTarBz2:
+		return unpackCompressedTarArchive(filePath, destPath, bzip2Decompressor, opts)
+	case ArchiveFormatTarZst:
+		return unpackCompressedTarArchive(filePath, destPath, zstdDecompressor, opts)
+	}
+
+	return fmt.Errorf("%w %s", ErrUnknownArchiveFormat, format)
+}
+
+// entryPath maps an archive entry name to its path relative to the destination, applying
+// opts.StripComponents and opts.Include. Entries that should not be extracted return false.
+func (opts Options) entryPath(name string) (string, bool) {
+	cleaned := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if cleaned == "." || cleaned == "/" {
+		return "", false
+	}
+
+	components := strings.Split(strings.TrimPrefix(cleaned, "/"), "/")
+	if len(components) <= opts.StripComponents {
+		return "", false
+	}
+
+	rel := path.Join(components[opts.StripComponents:]...)
+
+	if len(opts.Include) == 0 {
+		return rel, true
+	}
+
+	for _, pattern := range opts.Include {
+		// a pattern matching a parent directory includes the entry
+		for p := rel; p != "."; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return rel, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func unpackZipArchive(filePath string, destPath string, opts Options) error {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("error constructing new ZIP reader: %w", err)
+	}
+	defer archive.Close()
+
+	err = os.MkdirAll(destPath, 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %w", destPath, err)
+	}
+
+	for _, compressed := range archive.File {
+		err = unpackZipFile(compressed, destPath, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unpackZipFile(compressed *zip.File, destPath string, opts Options) error {
+	_, err := safeJoin(destPath, compressed.Name)
+	if err != nil {
+		return err
+	}
+
+	rel, ok := opts.entryPath(compressed.Name)
+	if !ok {
+		return nil
+	}
+
+	destFilePath := path.Join(destPath, rel)
+
+	err = checkParents(destPath, destFilePath, compressed.Name)
+	if err != nil {
+		return err
+	}
+
+	if compressed.Mode().IsDir() {
+		err = os.MkdirAll(destFilePath, 0o755)
+		if err != nil {
+			return fmt.Errorf("error creating directory %s: %w", destFilePath, err)
+		}
+
+		return nil
+	}
+
+	f, err := compressed.Open()
+	if err != nil {
+		return fmt.Errorf("error openening compressed file %s: %w", compressed.Name, err)
+	}
+	defer f.Close()
+
+	if compressed.Mode()&fs.ModeSymlink != 0 {
+		linkname, err := io.ReadAll(io.LimitReader(f, 4096))
+		if err != nil {
+			return fmt.Errorf("error reading symlink %s: %w", compressed.Name, err)
+		}
+
+		return writeSymlink(destPath, destFilePath, compressed.Name, string(linkname))
+	}
+
+	if !compressed.Mode().IsRegular() {
+		return nil
+	}
+
+	err = writeFile(destFilePath, f, compressed.Mode())
+	if err != nil {
+		return fmt.Errorf("error decompressing file %s to %s: %w", compressed.Name, destFilePath, err)
+	}
+
+	return nil
+}
+
+// unpackBinary places a single, optionally compressed, executable at destPath/binName.
+func unpackBinary(filePath string, destPath string, binName string, decompress decompressor) error {
+	if binName == "" || path.Base(binName) != binName {
+		return fmt.Errorf("error unpacking binary: invalid binary name '%s'", binName)
+	}
+
+	r, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if decompress != nil {
+		dr, err := decompress(r)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+
+		src = dr
+	}
+
+	err = os.MkdirAll(destPath, 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %w", destPath, err)
+	}
+
+	destFilePath := path.Join(destPath, binName)
+
+	err = writeFile(destFilePath, src, 0o755)
+	if err != nil {
+		return fmt.Errorf("error writing binary %s: %w", destFilePath, err)
+	}
+
+	return nil
+}
+
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+func gzipDecompressor(r io.Reader) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing new gzip reader: %w", err)
+	}
+
+	return zr, nil
+}
+
+func xzDecompressor(r io.Reader) (io.ReadCloser, error) {
+	xr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing new xz reader: %w", err)
+	}
+
+	return io.NopCloser(xr), nil
+}
+
+func bzip2Decompressor(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(bzip2.NewReader(r)), nil
+}
+
+func zstdDecompressor(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing new zstd reader: %w", err)
+	}
+
+	return zr.IOReadCloser(), nil
+}
+
+func unpackCompressedTarArchive(filePath string, destPath string, decompress decompressor, opts Options) error {
+	r, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dr, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+
+	return unpackTarReader(dr, destPath, opts)
+}
+
+func unpackTarArchive(filePath string, destPath string, opts Options) error {
+	r, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return unpackTarReader(r, destPath, opts)
+}
+
+func unpackTarReader(r io.Reader, destPath string, opts Options) error {
+	archive := tar.NewReader(r)
+
+	err := os.MkdirAll(destPath, 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %w", destPath, err)
+	}
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = safeJoin(destPath, header.Name)
+		if err != nil {
+			return err
+		}
+
+		rel, ok := opts.entryPath(header.Name)
+		if !ok {
+			continue
+		}
+
+		destFilePath := path.Join(destPath, rel)
+
+		err = unpackTarEntry(archive, header, destPath, destFilePath, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unpackTarEntry(archive *tar.Reader, header *tar.Header, destPath string, destFilePath string, opts Options) error {
+	err := checkParents(destPath, destFilePath, header.Name)
+	if err != nil {
+		return err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		err = os.MkdirAll(destFilePath, 0o755)
+		if err != nil {
+			return fmt.Errorf("error creating directory %s: %w", destFilePath, err)
+		}
+	case tar.TypeReg:
+		err = writeFile(destFilePath, archive, header.FileInfo().Mode())
+		if err != nil {
+			return fmt.Errorf("error decompressing file %s to %s: %w", header.Name, destFilePath, err)
+		}
+	case tar.TypeSymlink:
+		return writeSymlink(destPath, destFilePath, header.Name, header.Linkname)
+	case tar.TypeLink:
+		err = checkHardlink(destPath, header.Name, header.Linkname)
+		if err != nil {
+			return err
+		}
+
+		// hardlink targets are relative to the archive root and have to be mapped like any other entry
+		target, ok := opts.entryPath(header.Linkname)
+		if !ok {
+			return fmt.Errorf("error linking %s: target %s is not extracted", header.Name, header.Linkname)
+		}
+
+		return writeHardlink(destPath, destFilePath, header.Name, path.Join(destPath, target))
+	}
+
+	// devices, FIFOs and other special files are never needed to run a tool and are skipped
+	return nil
+}
+
+func writeSymlink(destPath string, destFilePath string, name string, linkname string) error {
+	err := checkSymlink(destPath, destFilePath, name, linkname)
+	if err != nil {
+		return err
+	}
+
+	err = prepareDest(destFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Symlink(filepath.FromSlash(strings.ReplaceAll(linkname, `\`, "/")), destFilePath)
+	if err != nil {
+		return fmt.Errorf("error creating symlink %s: %w", destFilePath, err)
+	}
+
+	return nil
+}
+
+func writeHardlink(destPath string, destFilePath string, name string, targetPath string) error {
+	err := checkParents(destPath, targetPath, name)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(targetPath)
+	if err != nil {
+		return fmt.Errorf("error linking %s: %w", name, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return &UnsafeEntryError{Entry: name, Reason: "hardlink to non-regular file"}
+	}
+
+	err = prepareDest(destFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(targetPath, destFilePath)
+	if err != nil {
+		return fmt.Errorf("error creating hardlink %s: %w", destFilePath, err)
+	}
+
+	return nil
+}
+
+// prepareDest creates the parent directory of destFilePath and removes existing files, so that
+// an earlier symlink entry of the same name is replaced instead of written through.
+func prepareDest(destFilePath string) error {
+	err := os.MkdirAll(path.Dir(destFilePath), 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %w", path.Dir(destFilePath), err)
+	}
+
+	err = os.Remove(destFilePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error replacing %s: %w", destFilePath, err)
+	}
+
+	return nil
+}
+
+// normalizeMode drops setuid, setgid and sticky bits as well as group and world write permissions.
+// Files executable by anyone become 0755, all others 0644.
+func normalizeMode(mode fs.FileMode) fs.FileMode {
+	if mode.Perm()&0o111 != 0 {
+		return 0o755
+	}
+
+	return 0o644
+}
+
+func writeFile(destFilePath string, r io.Reader, mode fs.FileMode) error {
+	err := prepareDest(destFilePath)
+	if err != nil {
+		return err
+	}
+
+	destFile, err := os.OpenFile(destFilePath, os.O_EXCL|os.O_WRONLY|os.O_CREATE, normalizeMode(mode))
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(destFile, r)
+
+	return err
+}
@@ -0,0 +1,591 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+type testEntry struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+	mode     int64
+}
+
+func TestUnpackArchive_UnsafeEntries(t *testing.T) {
+	tt := []struct {
+		name    string
+		entries []testEntry
+		unsafe  string
+	}{
+		{name: "Safe", entries: []testEntry{{name: "tool/bin/tool", content: "tool"}, {name: "tool/link", typeflag: tar.TypeSymlink, linkname: "bin/tool"}}},
+		{name: "Path Traversal", entries: []testEntry{{name: "../../evil", content: "evil"}}, unsafe: "../../evil"},
+		{name: "Nested Path Traversal", entries: []testEntry{{name: "tool/../../evil", content: "evil"}}, unsafe: "tool/../../evil"},
+		{name: "Absolute Path", entries: []testEntry{{name: "/tmp/evil", content: "evil"}}, unsafe: "/tmp/evil"},
+		{name: "Windows Path Traversal", entries: []testEntry{{name: `..\..\evil`, content: "evil"}}, unsafe: `..\..\evil`},
+		{name: "Symlink To Absolute Path", entries: []testEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}, unsafe: "link"},
+		{name: "Symlink Outside", entries: []testEntry{{name: "tool/link", typeflag: tar.TypeSymlink, linkname: "../../.bashrc"}}, unsafe: "tool/link"},
+		{name: "Symlink Chain", entries: []testEntry{{name: "b", typeflag: tar.TypeSymlink, linkname: "."}, {name: "a", typeflag: tar.TypeSymlink, linkname: "b/.."}}, unsafe: "a"},
+		{name: "Symlink Chain Reversed", entries: []testEntry{{name: "a", typeflag: tar.TypeSymlink, linkname: "b/.."}, {name: "b", typeflag: tar.TypeSymlink, linkname: "."}}, unsafe: "a"},
+	}
+
+	formats := map[ArchiveFormat]func(t *testing.T, entries []testEntry) []byte{
+		ArchiveFormatTar:    newTar,
+		ArchiveFormatTarGz:  newTarGzFromEntries,
+		ArchiveFormatTarXz:  newTarXz,
+		ArchiveFormatTarZst: newTarZst,
+		ArchiveFormatZip:    newZip,
+	}
+
+	for _, tt := range tt {
+		for format, newArchive := range formats {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				dir := t.TempDir()
+				archivePath := path.Join(dir, "archive")
+				destPath := path.Join(dir, "a", "b", "dest")
+
+				err := os.WriteFile(archivePath, newArchive(t, tt.entries), 0o644)
+				require.NoError(t, err)
+
+				err = unpackArchive(archivePath, destPath, format, Options{})
+				if tt.unsafe == "" {
+					assert.NoError(t, err)
+					return
+				}
+
+				var unsafeErr *UnsafeEntryError
+				require.ErrorAs(t, err, &unsafeErr)
+				assert.Equal(t, tt.unsafe, unsafeErr.Entry)
+				assert.NoFileExists(t, path.Join(dir, "evil"))
+				assert.NoFileExists(t, path.Join(dir, "a", "evil"))
+			})
+		}
+	}
+
+	t.Run("Hardlink Outside/tar", func(t *testing.T) {
+		dir := t.TempDir()
+		archivePath := path.Join(dir, "archive")
+
+		err := os.WriteFile(archivePath, newTar(t, []testEntry{{name: "tool/link", typeflag: tar.TypeLink, linkname: "../../etc/passwd"}}), 0o644)
+		require.NoError(t, err)
+
+		err = unpackArchive(archivePath, path.Join(dir, "dest"), ArchiveFormatTar, Options{})
+
+		var unsafeErr *UnsafeEntryError
+		require.ErrorAs(t, err, &unsafeErr)
+		assert.Equal(t, "tool/link", unsafeErr.Entry)
+	})
+}
+
+func TestUnpackArchive_TarEntryTypes(t *testing.T) {
+	tt := []struct {
+		name    string
+		entries []testEntry
+		opts    Options
+		unsafe  string
+		wantErr bool
+		check   func(t *testing.T, destPath string)
+	}{
+		{
+			name:    "Regular File Permissions",
+			entries: []testEntry{{name: "bin/tool", content: "tool", mode: 0o4777}, {name: "README", content: "readme", mode: 0o666}},
+			check: func(t *testing.T, destPath string) {
+				assertMode(t, path.Join(destPath, "bin/tool"), 0o755)
+				assertMode(t, path.Join(destPath, "README"), 0o644)
+			},
+		},
+		{
+			name:    "Directory",
+			entries: []testEntry{{name: "share/man/", typeflag: tar.TypeDir, mode: 0o777}},
+			check: func(t *testing.T, destPath string) {
+				assert.DirExists(t, path.Join(destPath, "share/man"))
+				assertMode(t, path.Join(destPath, "share/man"), fs.ModeDir|0o755)
+			},
+		},
+		{
+			name: "Symlink",
+			entries: []testEntry{
+				{name: "libexec/tool", content: "tool"},
+				{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "../libexec/tool"},
+			},
+			check: func(t *testing.T, destPath string) {
+				linkname, err := os.Readlink(path.Join(destPath, "bin/tool"))
+				require.NoError(t, err)
+				assert.Equal(t, "../libexec/tool", linkname)
+				assertContent(t, path.Join(destPath, "bin/tool"), "tool")
+			},
+		},
+		{
+			name: "Symlink Replaced By File",
+			entries: []testEntry{
+				{name: "target", content: "target"},
+				{name: "tool", typeflag: tar.TypeSymlink, linkname: "target"},
+				{name: "tool", content: "tool"},
+			},
+			check: func(t *testing.T, destPath string) {
+				assertContent(t, path.Join(destPath, "tool"), "tool")
+				assertContent(t, path.Join(destPath, "target"), "target")
+			},
+		},
+		{
+			name:    "Write Through Symlink",
+			entries: []testEntry{{name: "tool/up", typeflag: tar.TypeSymlink, linkname: ".."}, {name: "tool/up/evil", content: "evil"}},
+			unsafe:  "tool/up/evil",
+		},
+		{
+			name: "Hardlink",
+			entries: []testEntry{
+				{name: "tool-1.0.0/bin/tool", content: "tool"},
+				{name: "tool-1.0.0/bin/tool-alias", typeflag: tar.TypeLink, linkname: "tool-1.0.0/bin/tool"},
+			},
+			check: func(t *testing.T, destPath string) {
+				assertSameFile(t, path.Join(destPath, "tool-1.0.0/bin/tool"), path.Join(destPath, "tool-1.0.0/bin/tool-alias"))
+			},
+		},
+		{
+			name: "Hardlink Strip Components",
+			entries: []testEntry{
+				{name: "tool-1.0.0/bin/tool", content: "tool"},
+				{name: "tool-1.0.0/bin/tool-alias", typeflag: tar.TypeLink, linkname: "tool-1.0.0/bin/tool"},
+			},
+			opts: Options{StripComponents: 1},
+			check: func(t *testing.T, destPath string) {
+				assertSameFile(t, path.Join(destPath, "bin/tool"), path.Join(destPath, "bin/tool-alias"))
+			},
+		},
+		{
+			name: "Hardlink To Excluded Entry",
+			entries: []testEntry{
+				{name: "lib/tool", content: "tool"},
+				{name: "bin/tool", typeflag: tar.TypeLink, linkname: "lib/tool"},
+			},
+			opts:    Options{Include: []string{"bin"}},
+			wantErr: true,
+		},
+		{
+			name: "Hardlink To Symlink",
+			entries: []testEntry{
+				{name: "tool/link", typeflag: tar.TypeSymlink, linkname: "bin"},
+				{name: "tool/hard", typeflag: tar.TypeLink, linkname: "tool/link"},
+			},
+			unsafe: "tool/hard",
+		},
+		{
+			name:    "Device",
+			entries: []testEntry{{name: "dev/null", typeflag: tar.TypeChar}, {name: "fifo", typeflag: tar.TypeFifo}},
+			check: func(t *testing.T, destPath string) {
+				assert.NoFileExists(t, path.Join(destPath, "dev/null"))
+				assert.NoFileExists(t, path.Join(destPath, "fifo"))
+			},
+		},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := path.Join(dir, "archive")
+			destPath := path.Join(dir, "dest")
+
+			err := os.WriteFile(archivePath, newTar(t, tt.entries), 0o644)
+			require.NoError(t, err)
+
+			err = unpackArchive(archivePath, destPath, ArchiveFormatTar, tt.opts)
+
+			switch {
+			case tt.unsafe != "":
+				var unsafeErr *UnsafeEntryError
+				require.ErrorAs(t, err, &unsafeErr)
+				assert.Equal(t, tt.unsafe, unsafeErr.Entry)
+				assert.NoFileExists(t, path.Join(dir, "evil"))
+			case tt.wantErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				tt.check(t, destPath)
+			}
+		})
+	}
+}
+
+func TestUnpackArchive_SymlinkChains(t *testing.T) {
+	tt := []struct {
+		name    string
+		entries []testEntry
+		unsafe  string
+		check   func(t *testing.T, destPath string)
+	}{
+		{
+			name: "Relative Parent Link",
+			entries: []testEntry{
+				{name: "lib/tool", content: "tool"},
+				{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "../lib/tool"},
+			},
+			check: func(t *testing.T, destPath string) {
+				assertContent(t, path.Join(destPath, "bin/tool"), "tool")
+			},
+		},
+		{
+			name: "Link Through Earlier Link",
+			entries: []testEntry{
+				{name: "lib64/tool", content: "tool"},
+				{name: "lib", typeflag: tar.TypeSymlink, linkname: "lib64"},
+				{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "../lib/tool"},
+			},
+			check: func(t *testing.T, destPath string) {
+				assertContent(t, path.Join(destPath, "bin/tool"), "tool")
+			},
+		},
+		{
+			name: "Chain Of Links",
+			entries: []testEntry{
+				{name: "tool-1.0.0", content: "tool"},
+				{name: "tool-1", typeflag: tar.TypeSymlink, linkname: "tool-1.0.0"},
+				{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "../tool-1"},
+			},
+			check: func(t *testing.T, destPath string) {
+				assertContent(t, path.Join(destPath, "bin/tool"), "tool")
+			},
+		},
+		{
+			name:    "Relative Parent Link Outside",
+			entries: []testEntry{{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "../../tool"}},
+			unsafe:  "bin/tool",
+		},
+		{
+			name: "Parent Of Earlier Link",
+			entries: []testEntry{
+				{name: "b", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "a", typeflag: tar.TypeSymlink, linkname: "b/.."},
+			},
+			unsafe: "a",
+		},
+		{
+			name: "Parent Through Earlier Parent Link",
+			entries: []testEntry{
+				{name: "sub/up", typeflag: tar.TypeSymlink, linkname: ".."},
+				{name: "escape", typeflag: tar.TypeSymlink, linkname: "sub/up/.."},
+			},
+			unsafe: "escape",
+		},
+		{
+			name: "Write Through Earlier Link",
+			entries: []testEntry{
+				{name: "sub/up", typeflag: tar.TypeSymlink, linkname: ".."},
+				{name: "sub/up/evil", content: "evil"},
+			},
+			unsafe: "sub/up/evil",
+		},
+	}
+
+	formats := map[ArchiveFormat]func(t *testing.T, entries []testEntry) []byte{
+		ArchiveFormatTar: newTar,
+		ArchiveFormatZip: newZip,
+	}
+
+	for _, tt := range tt {
+		for format, newArchive := range formats {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				dir := t.TempDir()
+				archivePath := path.Join(dir, "archive")
+				destPath := path.Join(dir, "dest")
+
+				err := os.WriteFile(archivePath, newArchive(t, tt.entries), 0o644)
+				require.NoError(t, err)
+
+				err = unpackArchive(archivePath, destPath, format, Options{})
+				if tt.unsafe != "" {
+					var unsafeErr *UnsafeEntryError
+					require.ErrorAs(t, err, &unsafeErr)
+					assert.Equal(t, tt.unsafe, unsafeErr.Entry)
+					assert.NoFileExists(t, path.Join(dir, "evil"))
+					return
+				}
+
+				require.NoError(t, err)
+				tt.check(t, destPath)
+			})
+		}
+	}
+}
+
+func assertMode(t *testing.T, filePath string, expected fs.FileMode) {
+	t.Helper()
+
+	info, err := os.Lstat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, info.Mode())
+}
+
+func assertContent(t *testing.T, filePath string, expected string) {
+	t.Helper()
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
+
+func assertSameFile(t *testing.T, a string, b string) {
+	t.Helper()
+
+	infoA, err := os.Stat(a)
+	require.NoError(t, err)
+
+	infoB, err := os.Stat(b)
+	require.NoError(t, err)
+
+	assert.True(t, os.SameFile(infoA, infoB), "%s and %s are not the same file", a, b)
+}
+
+// tool-1.0.0/tool containing "tool\n", as Go has no bzip2 compressor.
+const testTarBz2 = "QlpoOTFBWSZTWbIc83MAAHh7gMmQAAJAA/KAIABgBJ4ACAggAFQyQI0YRoxoEkUDEDTJkH1dh0INMUIRxacBpQzQIYIuNPA3YXJk4Iswh/joolZ24OcAocKudLqJJA2LuSKcKEhZDnm5gA=="
+
+func TestUnpackArchive_Formats(t *testing.T) {
+	entries := []testEntry{{name: "tool-1.0.0/tool", content: "tool\n"}}
+
+	bz2, err := base64.StdEncoding.DecodeString(testTarBz2)
+	require.NoError(t, err)
+
+	formats := map[ArchiveFormat][]byte{
+		ArchiveFormatTar:    newTar(t, entries),
+		ArchiveFormatTarGz:  newTarGzFromEntries(t, entries),
+		ArchiveFormatTarXz:  newTarXz(t, entries),
+		ArchiveFormatTarBz2: bz2,
+		ArchiveFormatTarZst: newTarZst(t, entries),
+		ArchiveFormatZip:    newZip(t, entries),
+	}
+
+	for format, archive := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := path.Join(dir, "archive")
+			destPath := path.Join(dir, "dest")
+
+			err := os.WriteFile(archivePath, archive, 0o644)
+			require.NoError(t, err)
+
+			detected, ok := sniffArchiveFormat(archivePath)
+			require.True(t, ok)
+			assert.Equal(t, format, detected)
+
+			err = unpackArchive(archivePath, destPath, format, Options{})
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(path.Join(destPath, "tool-1.0.0", "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, "tool\n", string(content))
+		})
+	}
+}
+
+func TestUnpackArchive_StripComponentsAndInclude(t *testing.T) {
+	entries := []testEntry{
+		{name: "protoc-28.3/", typeflag: tar.TypeDir},
+		{name: "protoc-28.3/bin/", typeflag: tar.TypeDir},
+		{name: "protoc-28.3/bin/protoc", content: "protoc"},
+		{name: "protoc-28.3/include/google/protobuf/any.proto", content: "any"},
+		{name: "protoc-28.3/readme.txt", content: "readme"},
+		{name: "protoc-28.3/docs/readme.txt", content: "docs"},
+	}
+
+	tt := []struct {
+		name     string
+		opts     Options
+		expected map[string]string
+	}{
+		{
+			name: "Preserve Tree",
+			expected: map[string]string{
+				"protoc-28.3/bin/protoc":                        "protoc",
+				"protoc-28.3/include/google/protobuf/any.proto": "any",
+				"protoc-28.3/readme.txt":                        "readme",
+				"protoc-28.3/docs/readme.txt":                   "docs",
+			},
+		},
+		{
+			name: "Strip Components",
+			opts: Options{StripComponents: 1},
+			expected: map[string]string{
+				"bin/protoc":                        "protoc",
+				"include/google/protobuf/any.proto": "any",
+				"readme.txt":                        "readme",
+				"docs/readme.txt":                   "docs",
+			},
+		},
+		{
+			name:     "Strip All",
+			opts:     Options{StripComponents: 3},
+			expected: map[string]string{"protobuf/any.proto": "any"},
+		},
+		{
+			name:     "Include File",
+			opts:     Options{StripComponents: 1, Include: []string{"bin/protoc"}},
+			expected: map[string]string{"bin/protoc": "protoc"},
+		},
+		{
+			name: "Include Directory",
+			opts: Options{StripComponents: 1, Include: []string{"bin", "include"}},
+			expected: map[string]string{
+				"bin/protoc":                        "protoc",
+				"include/google/protobuf/any.proto": "any",
+			},
+		},
+		{
+			name:     "Include Glob",
+			opts:     Options{Include: []string{"*/*.txt"}},
+			expected: map[string]string{"protoc-28.3/readme.txt": "readme"},
+		},
+	}
+
+	formats := map[ArchiveFormat]func(t *testing.T, entries []testEntry) []byte{
+		ArchiveFormatTar:   newTar,
+		ArchiveFormatTarGz: newTarGzFromEntries,
+		ArchiveFormatZip:   newZip,
+	}
+
+	for _, tt := range tt {
+		for format, newArchive := range formats {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				dir := t.TempDir()
+				archivePath := path.Join(dir, "archive")
+				destPath := path.Join(dir, "dest")
+
+				err := os.WriteFile(archivePath, newArchive(t, entries), 0o644)
+				require.NoError(t, err)
+
+				err = unpackArchive(archivePath, destPath, format, tt.opts)
+				require.NoError(t, err)
+
+				actual := map[string]string{}
+				err = filepath.WalkDir(destPath, func(p string, d fs.DirEntry, err error) error {
+					if err != nil || d.IsDir() {
+						return err
+					}
+
+					content, err := os.ReadFile(p)
+					if err != nil {
+						return err
+					}
+
+					rel, err := filepath.Rel(destPath, p)
+					actual[filepath.ToSlash(rel)] = string(content)
+
+					return err
+				})
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.expected, actual)
+			})
+		}
+	}
+}
+
+func newTar(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o755, Typeflag: entry.typeflag, Linkname: entry.linkname}
+		if entry.mode != 0 {
+			header.Mode = entry.mode
+		}
+
+		if header.Typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(entry.content))
+		}
+
+		require.NoError(t, tw.WriteHeader(header))
+
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func newTarGzFromEntries(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+
+	_, err := zw.Write(newTar(t, entries))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func newTarXz(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	require.NoError(t, err)
+
+	_, err = xw.Write(newTar(t, entries))
+	require.NoError(t, err)
+	require.NoError(t, xw.Close())
+
+	return buf.Bytes()
+}
+
+func newTarZst(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+
+	_, err = zw.Write(newTar(t, entries))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func newZip(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name}
+		header.SetMode(0o755)
+
+		content := entry.content
+		if entry.typeflag == tar.TypeSymlink {
+			header.SetMode(fs.ModeSymlink | 0o777)
+			content = entry.linkname
+		}
+
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
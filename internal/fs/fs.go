@@ -1,11 +1,13 @@
 package fs
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
-	"path/filepath"
 )
 
 func FileExists(file string) (bool, error) {
@@ -25,16 +27,21 @@ func FileExists(file string) (bool, error) {
 	return true, nil
 }
 
-func SymlinkExists(name string, versionedBin string, targetDir string, srcDir string) (bool, error) {
-	versionedBinPath := path.Join(srcDir, versionedBin)
-	localFinalBinPath := path.Join(targetDir, name)
+func FileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("error opening file '%s': %v", file, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
 
-	symlinked, err := filepath.EvalSymlinks(localFinalBinPath)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return false, fmt.Errorf("error checking if tool %s has already been linked: %v", name, err)
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", fmt.Errorf("error hashing file '%s': %v", file, err)
 	}
 
-	return symlinked == versionedBinPath, nil
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func Symlink(name string, versionedBin string, targetDir string, srcDir string) error {
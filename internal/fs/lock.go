@@ -0,0 +1,107 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// lockPollInterval is how often Lock retries to acquire a lock held by another process.
+const lockPollInterval = 50 * time.Millisecond
+
+// FileLock is an advisory, exclusive lock on a file, held until Unlock is called.
+// Locks are shared between processes and between FileLocks within the same process.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock blocks until it acquired the lock on the file at lockPath or ctx is done.
+// The file and its parent directories are created if necessary.
+func Lock(ctx context.Context, lockPath string) (*FileLock, error) {
+	for {
+		lock, err := TryLock(lockPath)
+		if err != nil || lock != nil {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error locking %s: %w", lockPath, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// TryLock acquires the lock on the file at lockPath if it is not held by anyone else,
+// it returns nil if it is. The file and its parent directories are created if necessary.
+func TryLock(lockPath string) (*FileLock, error) {
+	err := os.MkdirAll(path.Dir(lockPath), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating lock directory %s: %w", path.Dir(lockPath), err)
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %s: %w", lockPath, err)
+	}
+
+	locked, err := tryLock(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %s: %w", lockPath, err)
+	}
+
+	if !locked {
+		f.Close()
+		return nil, nil
+	}
+
+	// the file may have been removed by its previous holder (see UnlockAndRemove) after it was opened,
+	// in which case the lock is worthless and has to be taken on the new file
+	if !isCurrentFile(f, lockPath) {
+		_ = unlock(f)
+		f.Close()
+
+		return nil, nil
+	}
+
+	return &FileLock{f: f}, nil
+}
+
+func isCurrentFile(f *os.File, filePath string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(opened, current)
+}
+
+func (l *FileLock) Unlock() error {
+	err := unlock(l.f)
+	if err != nil {
+		l.f.Close()
+		return fmt.Errorf("error unlocking %s: %w", l.f.Name(), err)
+	}
+
+	return l.f.Close()
+}
+
+// UnlockAndRemove removes the lock file and releases the lock. Anyone waiting for the lock
+// takes it on a newly created file.
+func (l *FileLock) UnlockAndRemove() error {
+	err := os.Remove(l.f.Name())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(fmt.Errorf("error removing lock file %s: %w", l.f.Name(), err), l.Unlock())
+	}
+
+	return l.Unlock()
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// file locking is only supported on unix systems, elsewhere concurrent invocations are not coordinated
+
+func tryLock(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(*os.File) error {
+	return nil
+}
@@ -0,0 +1,79 @@
+//go:build unix
+
+package fs
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), ".locks", "tool_1.0.0.lock")
+
+	first, err := Lock(context.Background(), lockPath)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+	defer cancel()
+
+	_, err = Lock(ctx, lockPath)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := make(chan *FileLock)
+	go func() {
+		second, err := Lock(context.Background(), lockPath)
+		assert.NoError(t, err)
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(2 * lockPollInterval):
+	}
+
+	require.NoError(t, first.Unlock())
+
+	second := <-acquired
+	require.NotNil(t, second)
+	require.NoError(t, second.Unlock())
+}
+
+func TestTryLock(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), ".locks", "tool", "1.0.0.lock")
+
+	first, err := TryLock(lockPath)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	second, err := TryLock(lockPath)
+	require.NoError(t, err)
+	assert.Nil(t, second)
+
+	acquired := make(chan *FileLock)
+	go func() {
+		waiting, err := Lock(context.Background(), lockPath)
+		assert.NoError(t, err)
+		acquired <- waiting
+	}()
+
+	// let the waiting Lock open the file before it is removed
+	time.Sleep(2 * lockPollInterval)
+
+	require.NoError(t, first.UnlockAndRemove())
+
+	waiting := <-acquired
+	require.NotNil(t, waiting)
+	assert.FileExists(t, lockPath, "the waiting lock is taken on a new file")
+
+	third, err := TryLock(lockPath)
+	require.NoError(t, err)
+	assert.Nil(t, third, "the new file is locked")
+
+	require.NoError(t, waiting.Unlock())
+}
@@ -4,32 +4,109 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"text/template"
 
 	"github.com/RobinThrift/toolfetcher/internal/fetch"
 	"github.com/RobinThrift/toolfetcher/recipes"
 )
 
-func InstallFromBinDownload(ctx context.Context, recipe *recipes.Recipe, version string, storeDir string) error {
+func InstallFromBinDownload(ctx context.Context, dl Downloader, recipe *recipes.Recipe, version string, destPath string) (Result, error) {
 	url, err := downloadURLForTool(recipe, version)
 	if err != nil {
-		return fmt.Errorf("%w: %s@%s: %v", ErrInstalling, recipe.Name, version, err)
+		return Result{}, fmt.Errorf("%w: %s@%s: %v", ErrInstalling, recipe.Name, version, err)
 	}
 
-	destPath := path.Join(storeDir, recipe.Name+"_"+version)
+	return downloadAndUnpack(ctx, dl, recipe, version, url, destPath)
+}
+
+func downloadAndUnpack(ctx context.Context, dl Downloader, recipe *recipes.Recipe, version string, url string, destPath string) (Result, error) {
+	if dl.OnResolve != nil {
+		dl.OnResolve(url)
+	}
+
+	checksum, err := checksumForTool(ctx, dl.Client, recipe, version, url)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s@%s: %w", ErrInstalling, recipe.Name, version, err)
+	}
 
-	err = fetch.DownloadAndUnpackTo(ctx, url, destPath)
+	downloaded, err := fetch.DownloadAndUnpackTo(ctx, url, destPath, fetch.Options{
+		SHA256:          checksum,
+		Format:          fetch.ArchiveFormat(recipe.Src.ArchiveFormat),
+		BinName:         recipe.Name,
+		StripComponents: recipe.Src.StripComponents,
+		Include:         recipe.Src.Include,
+		Client:          dl.Client,
+		PartialDir:      dl.PartialDir,
+		OnProgress:      dl.OnProgress,
+		OnExtract:       dl.OnExtract,
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %s@%s: %v", ErrInstalling, recipe.Name, version, err)
+		return Result{}, fmt.Errorf("%w: %s@%s: %w", ErrInstalling, recipe.Name, version, err)
+	}
+
+	if recipe.Src.BinPath == "" {
+		err = linkNestedBinary(destPath, recipe.Name)
+		if err != nil {
+			return Result{}, fmt.Errorf("%w: %s@%s: %w", ErrInstalling, recipe.Name, version, err)
+		}
 	}
 
-	return nil
+	return Result{URL: url, ArchiveSHA256: downloaded.SHA256}, nil
+}
+
+// linkNestedBinary makes recipes without a BinPath work with archives that keep the binary in a
+// subdirectory, e.g. "tool-1.0.0-linux-amd64/tool": when destPath/name is missing, the shallowest file
+// called name is symlinked to it.
+func linkNestedBinary(destPath string, name string) error {
+	binPath := path.Join(destPath, name)
+	if _, err := os.Lstat(binPath); err == nil {
+		return nil
+	}
+
+	var found string
+	err := filepath.WalkDir(destPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || (d.Name() != name && d.Name() != name+".exe") {
+			return nil
+		}
+
+		if found == "" || strings.Count(p, string(filepath.Separator)) < strings.Count(found, string(filepath.Separator)) {
+			found = p
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if found == "" {
+		return nil
+	}
+
+	target, err := filepath.Rel(destPath, found)
+	if err != nil {
+		return err
+	}
+
+	return os.Symlink(target, binPath)
 }
 
 func downloadURLForTool(recipe *recipes.Recipe, version string) (string, error) {
-	urlTmpl, err := template.New("").Parse(recipe.Src.URLTemplate)
+	return renderTemplate(recipe.Src.URLTemplate, recipe, version)
+}
+
+func renderTemplate(text string, recipe *recipes.Recipe, version string) (string, error) {
+	tmpl, err := template.New("").Parse(text)
 	if err != nil {
 		return "", fmt.Errorf("invalid template: %w", err)
 	}
@@ -49,16 +126,16 @@ func downloadURLForTool(recipe *recipes.Recipe, version string) (string, error)
 		}
 	}
 
-	var url bytes.Buffer
-	err = urlTmpl.Execute(&url, map[string]string{
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, map[string]string{
 		"Version": version,
 		"OS":      os,
 		"Arch":    arch,
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("error executing URL template: %w", err)
+		return "", fmt.Errorf("error executing template: %w", err)
 	}
 
-	return url.String(), nil
+	return rendered.String(), nil
 }
@@ -0,0 +1,46 @@
+package installer
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkNestedBinary(t *testing.T) {
+	tt := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{name: "Top Level", files: []string{"tool", "nested/tool"}, expected: "tool"},
+		{name: "Nested", files: []string{"tool-1.0.0-linux-amd64/tool", "tool-1.0.0-linux-amd64/README.md"}, expected: "tool-1.0.0-linux-amd64/tool"},
+		{name: "Shallowest", files: []string{"a/b/c/tool", "a/tool"}, expected: "a/tool"},
+		{name: "Windows", files: []string{"tool-1.0.0/tool.exe"}, expected: "tool-1.0.0/tool.exe"},
+		{name: "Missing", files: []string{"other/other"}},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := t.TempDir()
+			for _, f := range tt.files {
+				require.NoError(t, os.MkdirAll(path.Dir(path.Join(destPath, f)), 0o755))
+				require.NoError(t, os.WriteFile(path.Join(destPath, f), []byte(f), 0o755))
+			}
+
+			err := linkNestedBinary(destPath, "tool")
+			require.NoError(t, err)
+
+			if tt.expected == "" {
+				assert.NoFileExists(t, path.Join(destPath, "tool"))
+				return
+			}
+
+			content, err := os.ReadFile(path.Join(destPath, "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package installer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+func checksumForTool(ctx context.Context, client *fetch.Client, recipe *recipes.Recipe, version string, downloadURL string) (string, error) {
+	if checksum, ok := recipe.Src.SHA256[runtime.GOOS+"/"+runtime.GOARCH]; ok {
+		return checksum, nil
+	}
+
+	if recipe.Src.ChecksumURLTemplate == "" {
+		return "", nil
+	}
+
+	checksumURL, err := renderTemplate(recipe.Src.ChecksumURLTemplate, recipe, version)
+	if err != nil {
+		return "", fmt.Errorf("error rendering checksum URL: %w", err)
+	}
+
+	checksums, err := fetch.Download(ctx, client, checksumURL)
+	if err != nil {
+		return "", err
+	}
+
+	filename, err := assetFilename(downloadURL)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, ok := findChecksum(checksums, filename)
+	if !ok {
+		return "", fmt.Errorf("%w: no entry for '%s' in '%s'", ErrChecksumNotFound, filename, checksumURL)
+	}
+
+	return checksum, nil
+}
+
+func assetFilename(downloadURL string) (string, error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL '%s': %w", downloadURL, err)
+	}
+
+	return path.Base(u.Path), nil
+}
+
+// findChecksum supports the output format of sha256sum (in text and binary mode), the BSD style
+// "SHA256 (file) = digest" format and single digest files, like "tool.tar.gz.sha256".
+func findChecksum(checksums []byte, filename string) (string, bool) {
+	var lines [][]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 0 {
+			lines = append(lines, fields)
+		}
+	}
+
+	if len(lines) == 1 && len(lines[0]) == 1 && isSHA256(lines[0][0]) {
+		return lines[0][0], true
+	}
+
+	for _, fields := range lines {
+		if len(fields) == 4 && fields[0] == "SHA256" && fields[2] == "=" {
+			name := strings.TrimSuffix(strings.TrimPrefix(fields[1], "("), ")")
+			if path.Base(name) == filename && isSHA256(fields[3]) {
+				return fields[3], true
+			}
+
+			continue
+		}
+
+		if len(fields) != 2 || !isSHA256(fields[0]) {
+			continue
+		}
+
+		name := strings.TrimPrefix(fields[1], "*")
+		if path.Base(name) == filename {
+			return fields[0], true
+		}
+	}
+
+	return "", false
+}
+
+func isSHA256(s string) bool {
+	decoded, err := hex.DecodeString(s)
+	return err == nil && len(decoded) == 32
+}
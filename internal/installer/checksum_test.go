@@ -0,0 +1,74 @@
+package installer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindChecksum(t *testing.T) {
+	digestA := "4fc5a5cb8d2f1a41dbf4d3a1ff4d29ad30a64e9be6a8af0b1e3bfa56e4d2c8d1"
+	digestB := "0d4bbb3e4d3a7f6a16b5e0f8c3a1d2e7f9b8c6a5d4e3f2a1b0c9d8e7f6a5b4c3"
+
+	tt := []struct {
+		name      string
+		checksums string
+		filename  string
+		checksum  string
+		ok        bool
+	}{
+		{
+			name:      "sha256sum Text Mode",
+			checksums: digestA + "  tool_1.0.0_linux_amd64.tar.gz\n" + digestB + "  tool_1.0.0_darwin_arm64.tar.gz\n",
+			filename:  "tool_1.0.0_darwin_arm64.tar.gz",
+			checksum:  digestB,
+			ok:        true,
+		},
+		{
+			name:      "sha256sum Binary Mode",
+			checksums: digestA + " *tool_1.0.0_linux_amd64.tar.gz\n",
+			filename:  "tool_1.0.0_linux_amd64.tar.gz",
+			checksum:  digestA,
+			ok:        true,
+		},
+		{
+			name:      "Relative Paths",
+			checksums: digestA + "  ./dist/tool_1.0.0_linux_amd64.tar.gz\n",
+			filename:  "tool_1.0.0_linux_amd64.tar.gz",
+			checksum:  digestA,
+			ok:        true,
+		},
+		{
+			name:      "BSD Style",
+			checksums: "SHA256 (tool_1.0.0_linux_amd64.tar.gz) = " + digestA + "\n",
+			filename:  "tool_1.0.0_linux_amd64.tar.gz",
+			checksum:  digestA,
+			ok:        true,
+		},
+		{
+			name:      "Single Digest File",
+			checksums: digestA + "\n",
+			filename:  "tool_1.0.0_linux_amd64.tar.gz",
+			checksum:  digestA,
+			ok:        true,
+		},
+		{
+			name:      "Missing Entry",
+			checksums: digestA + "  tool_1.0.0_linux_amd64.tar.gz\n",
+			filename:  "tool_1.0.0_windows_amd64.zip",
+		},
+		{
+			name:      "Invalid Digest",
+			checksums: "not-a-digest  tool_1.0.0_linux_amd64.tar.gz\n",
+			filename:  "tool_1.0.0_linux_amd64.tar.gz",
+		},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, ok := findChecksum([]byte(tt.checksums), tt.filename)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.checksum, checksum)
+		})
+	}
+}
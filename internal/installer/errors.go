@@ -5,3 +5,5 @@ import (
 )
 
 var ErrInstalling = errors.New("error installing tool")
+var ErrChecksumNotFound = errors.New("checksum not found")
+var ErrNoMatchingAsset = errors.New("no matching release asset")
@@ -0,0 +1,194 @@
+package installer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+func InstallFromGitHubRelease(ctx context.Context, dl Downloader, recipe *recipes.Recipe, version string, destPath string) (Result, error) {
+	url, err := gitHubReleaseURLForTool(ctx, dl.Client, recipe, version)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s@%s: %w", ErrInstalling, recipe.Name, version, err)
+	}
+
+	return downloadAndUnpack(ctx, dl, recipe, version, url, destPath)
+}
+
+func gitHubReleaseURLForTool(ctx context.Context, client *fetch.Client, recipe *recipes.Recipe, version string) (string, error) {
+	if recipe.Src.URLTemplate != "" {
+		return downloadURLForTool(recipe, version)
+	}
+
+	if recipe.Src.Repo == "" {
+		return "", fmt.Errorf("missing GitHub repository")
+	}
+
+	assets, err := fetch.ListGitHubReleaseAssets(ctx, client, recipe.Src.Repo, "v"+version)
+	if errors.Is(err, fetch.ErrReleaseNotFound) {
+		assets, err = fetch.ListGitHubReleaseAssets(ctx, client, recipe.Src.Repo, version)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	asset, ok := selectAsset(assets, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return "", fmt.Errorf("%w for %s/%s in release %s of %s", ErrNoMatchingAsset, runtime.GOOS, runtime.GOARCH, version, recipe.Src.Repo)
+	}
+
+	return asset.URL, nil
+}
+
+var supportedAssetExts = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tar.zst", ".tar", ".zip"}
+
+var osAliases = newAliases(map[string][]string{
+	"linux":   {"linux"},
+	"darwin":  {"darwin", "macos", "apple", "osx", "mac"},
+	"windows": {"windows", "win64", "win32", "win"},
+	"freebsd": {"freebsd"},
+	"openbsd": {"openbsd"},
+	"netbsd":  {"netbsd"},
+})
+
+var archAliases = newAliases(map[string][]string{
+	"amd64":   {"amd64", "x86_64", "x86-64", "x64", "64bit"},
+	"arm64":   {"arm64", "aarch64"},
+	"386":     {"386", "i386", "i686", "x86", "32bit"},
+	"arm":     {"armv7", "armv6", "armhf", "arm"},
+	"ppc64le": {"ppc64le"},
+	"s390x":   {"s390x"},
+	"riscv64": {"riscv64"},
+})
+
+var (
+	muslPattern = aliasPattern("musl")
+	gnuPattern  = aliasPattern("gnu")
+)
+
+type alias struct {
+	key     string
+	name    string
+	pattern *regexp.Regexp
+}
+
+// newAliases compiles the aliases of every key, ordered by precedence: longer aliases first, so that
+// "x86_64" is detected as amd64 and not as 386 because of "x86", ties are broken by name.
+func newAliases(byKey map[string][]string) []alias {
+	var aliases []alias
+	for key, names := range byKey {
+		for _, name := range names {
+			aliases = append(aliases, alias{key: key, name: name, pattern: aliasPattern(name)})
+		}
+	}
+
+	slices.SortFunc(aliases, func(a, b alias) int {
+		if c := cmp.Compare(len(b.name), len(a.name)); c != 0 {
+			return c
+		}
+
+		return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.key, b.key))
+	})
+
+	return aliases
+}
+
+// selectAsset picks the release asset that fits goos and goarch best.
+// Assets for another OS or architecture are never selected, assets without an architecture
+// in their name (e.g. macOS universal binaries) only when there is no better match.
+func selectAsset(assets []fetch.GitHubReleaseAsset, goos string, goarch string) (fetch.GitHubReleaseAsset, bool) {
+	var best fetch.GitHubReleaseAsset
+	bestScore := 0
+
+	for _, asset := range assets {
+		score := scoreAsset(strings.ToLower(asset.Name), goos, goarch)
+		if score > bestScore || (score == bestScore && score > 0 && len(asset.Name) < len(best.Name)) {
+			best = asset
+			bestScore = score
+		}
+	}
+
+	return best, bestScore > 0
+}
+
+func scoreAsset(name string, goos string, goarch string) int {
+	ext, ok := assetExt(name)
+	if !ok {
+		return 0
+	}
+
+	name = strings.TrimSuffix(name, ext)
+
+	// prefer archives, they usually contain docs and licenses and are what checksum files are written for
+	score := 10
+	if ext != "" && ext != ".gz" && ext != ".exe" {
+		score += 3
+	}
+
+	if detectAlias(name, osAliases) != goos {
+		return 0
+	}
+
+	switch detectAlias(name, archAliases) {
+	case goarch:
+		score += 10
+	case "":
+		score++
+	default:
+		return 0
+	}
+
+	// statically linked musl builds work on any Linux, glibc builds don't
+	switch {
+	case muslPattern.MatchString(name):
+		score += 2
+	case gnuPattern.MatchString(name):
+		score++
+	}
+
+	return score
+}
+
+func assetExt(name string) (string, bool) {
+	for _, ext := range supportedAssetExts {
+		if strings.HasSuffix(name, ext) {
+			return ext, true
+		}
+	}
+
+	ext := path.Ext(name)
+	switch {
+	case ext == ".gz" || ext == ".exe":
+		return ext, true
+	// raw binaries have no extension, a dot in the name is usually part of the version, e.g. "tool-1.0.0-linux-amd64"
+	case ext == "" || strings.ContainsAny(ext, "-_"):
+		return "", true
+	}
+
+	return "", false
+}
+
+// detectAlias returns the key of the first alias found in name, see newAliases for their order.
+func detectAlias(name string, aliases []alias) string {
+	for _, a := range aliases {
+		if a.pattern.MatchString(name) {
+			return a.key
+		}
+	}
+
+	return ""
+}
+
+func aliasPattern(alias string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[-_. ])` + regexp.QuoteMeta(alias) + `($|[-_. ])`)
+}
@@ -0,0 +1,141 @@
+package installer
+
+import (
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectAsset(t *testing.T) {
+	gitCliff := assets(
+		"git-cliff-2.6.1-aarch64-apple-darwin.tar.gz",
+		"git-cliff-2.6.1-aarch64-apple-darwin.tar.gz.sha512",
+		"git-cliff-2.6.1-aarch64-unknown-linux-gnu.tar.gz",
+		"git-cliff-2.6.1-i686-unknown-linux-gnu.tar.gz",
+		"git-cliff-2.6.1-x86_64-apple-darwin.tar.gz",
+		"git-cliff-2.6.1-x86_64-pc-windows-msvc.zip",
+		"git-cliff-2.6.1-x86_64-unknown-linux-gnu.tar.gz",
+		"git-cliff-2.6.1-x86_64-unknown-linux-gnu.tar.gz.sha512",
+		"git-cliff-2.6.1-x86_64-unknown-linux-musl.tar.gz",
+		"git-cliff-2.6.1.tar.gz",
+	)
+
+	golangciLint := assets(
+		"checksums.txt",
+		"golangci-lint-1.61.0-darwin-amd64.tar.gz",
+		"golangci-lint-1.61.0-darwin-arm64.tar.gz",
+		"golangci-lint-1.61.0-linux-amd64.deb",
+		"golangci-lint-1.61.0-linux-amd64.tar.gz",
+		"golangci-lint-1.61.0-linux-arm64.tar.gz",
+		"golangci-lint-1.61.0-linux-armv7.tar.gz",
+		"golangci-lint-1.61.0-windows-386.zip",
+		"golangci-lint-1.61.0-windows-amd64.zip",
+	)
+
+	gum := assets(
+		"checksums.txt",
+		"gum_0.14.5_Darwin_arm64.tar.gz",
+		"gum_0.14.5_Darwin_x86_64.tar.gz",
+		"gum_0.14.5_Linux_arm64.tar.gz",
+		"gum_0.14.5_Linux_i386.tar.gz",
+		"gum_0.14.5_Linux_x86_64.tar.gz",
+		"gum_0.14.5_Windows_x86_64.zip",
+	)
+
+	universal := assets(
+		"tool_1.0.0_macos_universal.zip",
+		"tool_1.0.0_linux_amd64.tar.gz",
+	)
+
+	jq := assets(
+		"jq-1.7.1.tar.gz",
+		"jq-linux-amd64",
+		"jq-linux-arm64",
+		"jq-macos-arm64",
+		"jq-windows-amd64.exe",
+		"sha256sum.txt",
+	)
+
+	yq := assets(
+		"checksums",
+		"checksums.txt",
+		"yq_darwin_arm64",
+		"yq_darwin_arm64.tar.gz",
+		"yq_linux_amd64",
+		"yq_linux_amd64.tar.gz",
+		"yq_linux_amd64.tar.gz.sha256",
+		"yq_man_page_only.tar.gz",
+	)
+
+	gzipped := assets(
+		"tool-1.0.0-linux-amd64.gz",
+		"tool-1.0.0-linux-amd64.gz.sha256",
+		"tool-1.0.0-linux-amd64.sig",
+	)
+
+	tt := []struct {
+		name     string
+		assets   []fetch.GitHubReleaseAsset
+		goos     string
+		goarch   string
+		expected string
+	}{
+		{name: "git-cliff/linux/amd64", assets: gitCliff, goos: "linux", goarch: "amd64", expected: "git-cliff-2.6.1-x86_64-unknown-linux-musl.tar.gz"},
+		{name: "git-cliff/linux/arm64", assets: gitCliff, goos: "linux", goarch: "arm64", expected: "git-cliff-2.6.1-aarch64-unknown-linux-gnu.tar.gz"},
+		{name: "git-cliff/linux/386", assets: gitCliff, goos: "linux", goarch: "386", expected: "git-cliff-2.6.1-i686-unknown-linux-gnu.tar.gz"},
+		{name: "git-cliff/darwin/arm64", assets: gitCliff, goos: "darwin", goarch: "arm64", expected: "git-cliff-2.6.1-aarch64-apple-darwin.tar.gz"},
+		{name: "git-cliff/windows/amd64", assets: gitCliff, goos: "windows", goarch: "amd64", expected: "git-cliff-2.6.1-x86_64-pc-windows-msvc.zip"},
+		{name: "git-cliff/linux/riscv64", assets: gitCliff, goos: "linux", goarch: "riscv64"},
+		{name: "golangci-lint/linux/amd64", assets: golangciLint, goos: "linux", goarch: "amd64", expected: "golangci-lint-1.61.0-linux-amd64.tar.gz"},
+		{name: "golangci-lint/linux/arm", assets: golangciLint, goos: "linux", goarch: "arm", expected: "golangci-lint-1.61.0-linux-armv7.tar.gz"},
+		{name: "golangci-lint/darwin/amd64", assets: golangciLint, goos: "darwin", goarch: "amd64", expected: "golangci-lint-1.61.0-darwin-amd64.tar.gz"},
+		{name: "gum/linux/amd64", assets: gum, goos: "linux", goarch: "amd64", expected: "gum_0.14.5_Linux_x86_64.tar.gz"},
+		{name: "gum/darwin/arm64", assets: gum, goos: "darwin", goarch: "arm64", expected: "gum_0.14.5_Darwin_arm64.tar.gz"},
+		{name: "jq/linux/amd64", assets: jq, goos: "linux", goarch: "amd64", expected: "jq-linux-amd64"},
+		{name: "jq/darwin/arm64", assets: jq, goos: "darwin", goarch: "arm64", expected: "jq-macos-arm64"},
+		{name: "jq/windows/amd64", assets: jq, goos: "windows", goarch: "amd64", expected: "jq-windows-amd64.exe"},
+		{name: "yq/linux/amd64", assets: yq, goos: "linux", goarch: "amd64", expected: "yq_linux_amd64.tar.gz"},
+		{name: "gzipped/linux/amd64", assets: gzipped, goos: "linux", goarch: "amd64", expected: "tool-1.0.0-linux-amd64.gz"},
+		{name: "universal/darwin/arm64", assets: universal, goos: "darwin", goarch: "arm64", expected: "tool_1.0.0_macos_universal.zip"},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, ok := selectAsset(tt.assets, tt.goos, tt.goarch)
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, asset.Name)
+		})
+	}
+}
+
+func TestDetectAlias(t *testing.T) {
+	tt := []struct {
+		name     string
+		aliases  []alias
+		expected string
+	}{
+		{name: "tool-x86_64-linux", aliases: archAliases, expected: "amd64"},
+		{name: "tool-x86-linux", aliases: archAliases, expected: "386"},
+		{name: "tool-armv7-linux", aliases: archAliases, expected: "arm"},
+		{name: "tool-arm64-amd64", aliases: archAliases, expected: "amd64"},
+		{name: "tool-amd64-arm64", aliases: archAliases, expected: "amd64"},
+		{name: "tool-macos-linux", aliases: osAliases, expected: "linux"},
+		{name: "tool-1.0.0", aliases: osAliases, expected: ""},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, detectAlias(tt.name, tt.aliases))
+		})
+	}
+}
+
+func assets(names ...string) []fetch.GitHubReleaseAsset {
+	assets := make([]fetch.GitHubReleaseAsset, 0, len(names))
+	for _, name := range names {
+		assets = append(assets, fetch.GitHubReleaseAsset{Name: name, URL: "https://example.com/" + name})
+	}
+
+	return assets
+}
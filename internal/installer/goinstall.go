@@ -3,31 +3,38 @@ package installer
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 
 	"github.com/RobinThrift/toolfetcher/recipes"
 )
 
-func InstallWithGoInstall(ctx context.Context, recipe *recipes.Recipe, version string, storeDir string) error {
-	cmd := exec.CommandContext(ctx, "go", "install", recipe.Src.URLTemplate+"@v"+version)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Env = append(os.Environ(), "GOBIN="+storeDir)
+func InstallWithGoInstall(ctx context.Context, recipe *recipes.Recipe, version string, destPath string, stdout io.Writer, stderr io.Writer) (Result, error) {
+	pkg := recipe.Src.URLTemplate + "@v" + version
 
-	err := cmd.Run()
+	// go install requires GOBIN to be absolute
+	gobin, err := filepath.Abs(path.Dir(destPath))
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrInstalling, err)
+		return Result{}, fmt.Errorf("%w: %v", ErrInstalling, err)
 	}
 
-	versionedBinPath := path.Join(storeDir, recipe.Name+"_"+version)
+	cmd := exec.CommandContext(ctx, "go", "install", pkg)
+	cmd.Stderr = stderr
+	cmd.Stdout = stdout
+	cmd.Env = append(os.Environ(), "GOBIN="+gobin)
 
-	err = os.Rename(path.Join(storeDir, recipe.Name), versionedBinPath)
+	err = cmd.Run()
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrInstalling, err)
+		return Result{}, fmt.Errorf("%w: %v", ErrInstalling, err)
 	}
 
-	return nil
+	err = os.Rename(path.Join(gobin, recipe.Name), destPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrInstalling, err)
+	}
+
+	return Result{URL: pkg}, nil
 }
@@ -0,0 +1,45 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+// Downloader configures how the installers download files.
+type Downloader struct {
+	Client *fetch.Client
+
+	// PartialDir keeps interrupted downloads so that they can be resumed, see [fetch.Options].
+	PartialDir string
+
+	// OnResolve is called with the URL of the file to download, before the download starts.
+	OnResolve func(url string)
+
+	// OnProgress and OnExtract are passed on to [fetch.Options].
+	OnProgress func(downloaded int64, total int64)
+	OnExtract  func()
+}
+
+type Result struct {
+	// URL is the resolved location the tool was installed from.
+	URL string
+
+	// ArchiveSHA256 is the digest of the downloaded archive, if any.
+	ArchiveSHA256 string
+}
+
+func ResolveURL(ctx context.Context, client *fetch.Client, recipe *recipes.Recipe, version string) (string, error) {
+	switch recipe.Src.Type {
+	case recipes.SourceTypeGoInstall:
+		return recipe.Src.URLTemplate + "@v" + version, nil
+	case recipes.SourceTypeBinDownload:
+		return downloadURLForTool(recipe, version)
+	case recipes.SourceTypeGitHubRelease:
+		return gitHubReleaseURLForTool(ctx, client, recipe, version)
+	}
+
+	return "", fmt.Errorf("%w: %s@%s: unknown install method %s", ErrInstalling, recipe.Name, version, recipe.Src.Type)
+}
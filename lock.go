@@ -0,0 +1,205 @@
+package toolfetcher
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/RobinThrift/toolfetcher/internal/fs"
+	"github.com/RobinThrift/toolfetcher/lockfile"
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+type toolLock struct {
+	mu       sync.Mutex
+	filepath string
+	frozen   bool
+	file     *lockfile.LockFile
+	changed  bool
+}
+
+func (tf *ToolFetcher) readLockFile() (*toolLock, error) {
+	lockFilePath := tf.LockFile
+	if lockFilePath == "" {
+		lockFilePath = tf.VersionFile + ".lock"
+	}
+
+	file, err := lockfile.ReadFile(lockFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &toolLock{filepath: lockFilePath, frozen: tf.Frozen, file: file}, nil
+}
+
+// lockedBinary returns the binary digest locked for tool on the current platform, which is part of its
+// store entry. Binaries built by go install depend on the local Go toolchain, so their digests are only recorded.
+func (l *toolLock) lockedBinary(tool *Tool) string {
+	if l == nil || tool.Recipe.Src.Type == recipes.SourceTypeGoInstall {
+		return ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	locked, _ := l.file.Get(tool.Name, tool.Version, currentPlatform())
+
+	return locked.BinarySHA256
+}
+
+// verifyInstall checks a freshly installed tool against its lock entry, or records a new entry if there is none.
+// It returns the digest of the installed binary.
+func (l *toolLock) verifyInstall(tool *Tool, result InstallResult, storeDir string) (string, error) {
+	binarySHA256, err := fs.FileSHA256(path.Join(storeDir, tool.BinPath()))
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	platform := currentPlatform()
+	fingerprint := tool.Recipe.Fingerprint()
+
+	locked, ok := l.file.Get(tool.Name, tool.Version, platform)
+	if ok && (sameRecipe(locked, fingerprint) || l.frozen) {
+		// e.g. a new asset uploaded to an existing GitHub release, which would be selected instead of the locked one
+		if locked.URL != result.URL {
+			return "", fmt.Errorf("%w: %s: locked URL %s does not match %s", ErrLockMismatch, tool.VersionedName(), locked.URL, result.URL)
+		}
+
+		if locked.ArchiveSHA256 != "" && !strings.EqualFold(locked.ArchiveSHA256, result.ArchiveSHA256) {
+			return "", fmt.Errorf("%w: %s archive digest: expected %s, got %s", ErrLockMismatch, tool.VersionedName(), locked.ArchiveSHA256, result.ArchiveSHA256)
+		}
+
+		// binaries built by go install depend on the local Go toolchain, so their digests are only recorded
+		if tool.Recipe.Src.Type != recipes.SourceTypeGoInstall && !strings.EqualFold(locked.BinarySHA256, binarySHA256) {
+			return "", fmt.Errorf("%w: %s binary digest: expected %s, got %s", ErrLockMismatch, tool.VersionedName(), locked.BinarySHA256, binarySHA256)
+		}
+
+		l.recordFingerprint(tool, locked, fingerprint)
+
+		return binarySHA256, nil
+	}
+
+	if l.frozen {
+		return "", fmt.Errorf("%w for %s on %s", ErrMissingLockEntry, tool.VersionedName(), platform)
+	}
+
+	l.file.Set(tool.Name, tool.Version, platform, lockfile.Entry{
+		URL:           result.URL,
+		ArchiveSHA256: result.ArchiveSHA256,
+		BinarySHA256:  binarySHA256,
+		Fingerprint:   fingerprint,
+	})
+	l.changed = true
+
+	return binarySHA256, nil
+}
+
+// ensureEntry verifies a tool that is already in the store, whose binary has the digest binarySHA256,
+// against its lock entry, or records a new entry if the tool was installed before it was added to the lock file
+// or its recipe changed.
+func (l *toolLock) ensureEntry(ctx context.Context, tool *Tool, binarySHA256 string, resolver URLResolver) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	platform := currentPlatform()
+	fingerprint := tool.Recipe.Fingerprint()
+
+	locked, ok := l.file.Get(tool.Name, tool.Version, platform)
+	if ok {
+		// binaries built by go install depend on the local Go toolchain, so their digests are only recorded
+		if tool.Recipe.Src.Type != recipes.SourceTypeGoInstall && !strings.EqualFold(locked.BinarySHA256, binarySHA256) {
+			return fmt.Errorf("%w: %s binary digest in store: expected %s, got %s", ErrLockMismatch, tool.VersionedName(), locked.BinarySHA256, binarySHA256)
+		}
+
+		// the store entry was installed with the same recipe and verified binary, so the URL is the locked one
+		if sameRecipe(locked, fingerprint) {
+			l.recordFingerprint(tool, locked, fingerprint)
+			return nil
+		}
+	}
+
+	var url string
+	if resolver != nil {
+		var err error
+		url, err = resolver.ResolveURL(ctx, tool)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ok && locked.URL == url {
+		l.recordFingerprint(tool, locked, fingerprint)
+		return nil
+	}
+
+	if l.frozen {
+		if ok {
+			return fmt.Errorf("%w: %s: locked URL %s does not match %s", ErrLockMismatch, tool.VersionedName(), locked.URL, url)
+		}
+
+		return fmt.Errorf("%w for %s on %s", ErrMissingLockEntry, tool.VersionedName(), platform)
+	}
+
+	l.file.Set(tool.Name, tool.Version, platform, lockfile.Entry{
+		URL:          url,
+		BinarySHA256: binarySHA256,
+		Fingerprint:  fingerprint,
+	})
+	l.changed = true
+
+	return nil
+}
+
+// sameRecipe reports whether locked was locked with the recipe with the given fingerprint.
+// Entries written before fingerprints were recorded are assumed to be.
+func sameRecipe(locked lockfile.Entry, fingerprint string) bool {
+	return locked.Fingerprint == "" || locked.Fingerprint == fingerprint
+}
+
+// recordFingerprint records the fingerprint of the recipe a verified entry was locked with, if it changed
+// or is missing. l.mu must be held.
+func (l *toolLock) recordFingerprint(tool *Tool, locked lockfile.Entry, fingerprint string) {
+	if l.frozen || locked.Fingerprint == fingerprint {
+		return
+	}
+
+	locked.Fingerprint = fingerprint
+	l.file.Set(tool.Name, tool.Version, currentPlatform(), locked)
+	l.changed = true
+}
+
+func (l *toolLock) save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.frozen || !l.changed {
+		return nil
+	}
+
+	// another process may have added entries since the lock file was read
+	onDisk, err := lockfile.ReadFile(l.filepath)
+	if err != nil {
+		return err
+	}
+
+	l.file.Merge(onDisk)
+
+	err = l.file.WriteFile(l.filepath)
+	if err != nil {
+		return err
+	}
+
+	l.changed = false
+
+	return nil
+}
+
+func currentPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
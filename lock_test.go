@@ -0,0 +1,302 @@
+package toolfetcher
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/lockfile"
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_LockFile(t *testing.T) {
+	archive := newTestTarGz(t, "tool", "#!/bin/sh\necho v1\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	cwd := t.TempDir()
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: github-releases://example/tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	newFetcher := func(frozen bool) *ToolFetcher {
+		return &ToolFetcher{
+			VersionFile: toolfilePath,
+			BinDir:      path.Join(cwd, ".bin"),
+			Frozen:      frozen,
+			Recipes: []recipes.Recipe{{
+				Name: "tool",
+				Src: recipes.Source{
+					Type:        recipes.SourceTypeBinDownload,
+					URLTemplate: srv.URL + "/tool-{{ .Version }}.tar.gz",
+				},
+			}},
+		}
+	}
+
+	t.Run("Frozen Without Lock File", func(t *testing.T) {
+		err := newFetcher(true).FetchAll(context.Background())
+		assert.ErrorIs(t, err, ErrMissingLockEntry)
+		assert.NoFileExists(t, toolfilePath+".lock")
+	})
+
+	t.Run("Writes Lock File", func(t *testing.T) {
+		err := newFetcher(false).FetchAll(context.Background())
+		require.NoError(t, err)
+
+		lock, err := lockfile.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+
+		entry, ok := lock.Get("tool", "1.0.0", currentPlatform())
+		require.True(t, ok)
+		assert.Equal(t, srv.URL+"/tool-1.0.0.tar.gz", entry.URL)
+		assert.NotEmpty(t, entry.ArchiveSHA256)
+		assert.NotEmpty(t, entry.BinarySHA256)
+		assert.NotEmpty(t, entry.Fingerprint)
+	})
+
+	t.Run("Frozen With Lock File", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(path.Join(cwd, ".bin")))
+
+		err := newFetcher(true).FetchAll(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("Changed Artifact", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(path.Join(cwd, ".bin")))
+		archive = newTestTarGz(t, "tool", "#!/bin/sh\necho v2\n")
+
+		err := newFetcher(false).FetchAll(context.Background())
+		assert.ErrorIs(t, err, ErrLockMismatch)
+		assert.NoDirExists(t, path.Join(cwd, ".bin", ".store", "tool"))
+	})
+
+	t.Run("Changed URL", func(t *testing.T) {
+		archive = newTestTarGz(t, "tool", "#!/bin/sh\necho v1\n")
+
+		// e.g. a new asset uploaded to a GitHub release that is selected instead of the locked one
+		lock, err := lockfile.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+		entry, _ := lock.Get("tool", "1.0.0", currentPlatform())
+		entry.URL = srv.URL + "/other/tool-1.0.0.tar.gz"
+		lock.Set("tool", "1.0.0", currentPlatform(), entry)
+		require.NoError(t, lock.WriteFile(toolfilePath+".lock"))
+
+		err = newFetcher(false).FetchAll(context.Background())
+		assert.ErrorIs(t, err, ErrLockMismatch)
+		assert.ErrorContains(t, err, "locked URL")
+
+		lock, err = lockfile.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+		unchanged, _ := lock.Get("tool", "1.0.0", currentPlatform())
+		assert.Equal(t, entry, unchanged)
+	})
+
+	t.Run("Changed Recipe", func(t *testing.T) {
+		newMirrorFetcher := func(frozen bool) *ToolFetcher {
+			fetcher := newFetcher(frozen)
+			fetcher.Recipes[0].Src.URLTemplate = srv.URL + "/mirror/tool-{{ .Version }}.tar.gz"
+
+			return fetcher
+		}
+
+		err := newMirrorFetcher(true).FetchAll(context.Background())
+		assert.ErrorIs(t, err, ErrLockMismatch)
+
+		fetcher := newMirrorFetcher(false)
+		require.NoError(t, fetcher.FetchAll(context.Background()))
+
+		lock, err := lockfile.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+		entry, _ := lock.Get("tool", "1.0.0", currentPlatform())
+		assert.Equal(t, srv.URL+"/mirror/tool-1.0.0.tar.gz", entry.URL)
+		assert.Equal(t, fetcher.Recipes[0].Fingerprint(), entry.Fingerprint)
+	})
+}
+
+func TestToolFetcher_LockFile_VerifiesStore(t *testing.T) {
+	archive := newTestTarGz(t, "tool", "#!/bin/sh\necho v1\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	cwd := t.TempDir()
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: github-releases://example/tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	newFetcher := func(frozen bool) *ToolFetcher {
+		return &ToolFetcher{
+			VersionFile: toolfilePath,
+			BinDir:      path.Join(cwd, ".bin"),
+			StoreDir:    path.Join(cwd, "store"),
+			Frozen:      frozen,
+			Recipes: []recipes.Recipe{{
+				Name: "tool",
+				Src: recipes.Source{
+					Type:        recipes.SourceTypeBinDownload,
+					URLTemplate: srv.URL + "/tool-{{ .Version }}.tar.gz",
+				},
+			}},
+		}
+	}
+
+	err = newFetcher(false).FetchAll(context.Background())
+	require.NoError(t, err)
+
+	storeBins, err := filepath.Glob(path.Join(cwd, "store", "tool", "1.0.0", "*", "*", "tool"))
+	require.NoError(t, err)
+	require.Len(t, storeBins, 1)
+
+	// e.g. another project sharing the store installed a different binary
+	replaceStoreBin := func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(path.Join(cwd, ".bin")))
+		require.NoError(t, os.WriteFile(storeBins[0], []byte("#!/bin/sh\necho evil\n"), 0o755))
+	}
+
+	t.Run("Frozen", func(t *testing.T) {
+		replaceStoreBin(t)
+
+		lockBefore, err := os.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+
+		// reinstalling is checked against the existing entry, so it is safe without writing to the lock file
+		err = newFetcher(true).FetchAll(context.Background())
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(path.Join(cwd, ".bin", "tool"))
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho v1\n", string(content))
+
+		lockAfter, err := os.ReadFile(toolfilePath + ".lock")
+		require.NoError(t, err)
+		assert.Equal(t, string(lockBefore), string(lockAfter))
+	})
+
+	t.Run("Reinstalls", func(t *testing.T) {
+		replaceStoreBin(t)
+
+		err := newFetcher(false).FetchAll(context.Background())
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(path.Join(cwd, ".bin", "tool"))
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho v1\n", string(content))
+	})
+}
+
+func TestToolFetcher_LockFile_SharedStore(t *testing.T) {
+	v1, v2 := "#!/bin/sh\necho v1\n", "#!/bin/sh\necho v2\n"
+	archive := newTestTarGz(t, "tool", v1)
+	archiveV2 := newTestTarGz(t, "tool", v2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	storeDir := t.TempDir()
+
+	newProject := func() (*ToolFetcher, string) {
+		cwd := t.TempDir()
+		toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+		require.NoError(t, os.WriteFile(toolfilePath, []byte("tool: github-releases://example/tool@1.0.0\n"), 0o644))
+
+		return &ToolFetcher{
+			VersionFile: toolfilePath,
+			BinDir:      path.Join(cwd, ".bin"),
+			StoreDir:    storeDir,
+			Recipes: []recipes.Recipe{{
+				Name: "tool",
+				Src: recipes.Source{
+					Type:        recipes.SourceTypeBinDownload,
+					URLTemplate: srv.URL + "/tool-{{ .Version }}.tar.gz",
+				},
+			}},
+		}, cwd
+	}
+
+	assertLinked := func(t *testing.T, cwd string, expected string) {
+		t.Helper()
+
+		content, err := os.ReadFile(path.Join(cwd, ".bin", "tool"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+
+	projectA, cwdA := newProject()
+	require.NoError(t, projectA.FetchAll(context.Background()))
+
+	// project B locked an artifact that differs from the one project A installed
+	projectB, cwdB := newProject()
+	lock := &lockfile.LockFile{}
+	lock.Set("tool", "1.0.0", currentPlatform(), lockfile.Entry{
+		URL:           srv.URL + "/tool-1.0.0.tar.gz",
+		ArchiveSHA256: sha256Hex(archiveV2),
+		BinarySHA256:  sha256Hex([]byte(v2)),
+	})
+	require.NoError(t, lock.WriteFile(projectB.VersionFile+".lock"))
+
+	t.Run("Mismatch", func(t *testing.T) {
+		err := projectB.FetchAll(context.Background())
+		assert.ErrorIs(t, err, ErrLockMismatch)
+
+		assertLinked(t, cwdA, v1)
+		require.NoError(t, projectA.FetchAll(context.Background()))
+		assertLinked(t, cwdA, v1)
+	})
+
+	t.Run("Own Entry", func(t *testing.T) {
+		archive = archiveV2
+
+		require.NoError(t, projectB.FetchAll(context.Background()))
+		assertLinked(t, cwdB, v2)
+
+		require.NoError(t, projectA.FetchAll(context.Background()))
+		assertLinked(t, cwdA, v1)
+
+		entries, err := filepath.Glob(path.Join(storeDir, "tool", "1.0.0", "*", "*"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+}
+
+func sha256Hex(b []byte) string {
+	digest := sha256.Sum256(b)
+	return hex.EncodeToString(digest[:])
+}
+
+func newTestTarGz(t *testing.T, name string, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	require.NoError(t, err)
+
+	_, err = tw.Write([]byte(content))
+	require.NoError(t, err)
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
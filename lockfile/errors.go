@@ -0,0 +1,7 @@
+package lockfile
+
+import (
+	"errors"
+)
+
+var ErrParsingLockFile = errors.New("error parsing lock file")
@@ -0,0 +1,111 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+type LockFile struct {
+	// Tools maps "name@version" to the locked entries for each platform ("GOOS/GOARCH").
+	Tools map[string]map[string]Entry `json:"tools"`
+}
+
+type Entry struct {
+	URL           string `json:"url"`
+	ArchiveSHA256 string `json:"archive_sha256,omitempty"`
+	BinarySHA256  string `json:"binary_sha256"`
+
+	// Fingerprint is the fingerprint of the recipe the entry was locked with. Entries may only be
+	// locked anew once the recipe changed.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func Parse(r io.Reader) (*LockFile, error) {
+	lockFile := &LockFile{}
+
+	err := json.NewDecoder(r).Decode(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParsingLockFile, err)
+	}
+
+	if lockFile.Tools == nil {
+		lockFile.Tools = map[string]map[string]Entry{}
+	}
+
+	return lockFile, nil
+}
+
+func ReadFile(filepath string) (*LockFile, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &LockFile{Tools: map[string]map[string]Entry{}}, nil
+		}
+
+		return nil, fmt.Errorf("error opening lock file %s: %w", filepath, err)
+	}
+	defer file.Close()
+
+	lockFile, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath, err)
+	}
+
+	return lockFile, nil
+}
+
+func (l *LockFile) WriteFile(filepath string) error {
+	encoded, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding lock file: %w", err)
+	}
+
+	err = os.WriteFile(filepath, append(encoded, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing lock file %s: %w", filepath, err)
+	}
+
+	return nil
+}
+
+func (l *LockFile) Get(name string, version string, platform string) (Entry, bool) {
+	entry, ok := l.Tools[name+"@"+version][platform]
+	return entry, ok
+}
+
+func (l *LockFile) Set(name string, version string, platform string, entry Entry) {
+	if l.Tools == nil {
+		l.Tools = map[string]map[string]Entry{}
+	}
+
+	key := name + "@" + version
+	if l.Tools[key] == nil {
+		l.Tools[key] = map[string]Entry{}
+	}
+
+	l.Tools[key][platform] = entry
+}
+
+// Merge adds the entries of other that are missing in l. Existing entries are kept.
+func (l *LockFile) Merge(other *LockFile) {
+	for key, platforms := range other.Tools {
+		for platform, entry := range platforms {
+			if _, ok := l.Tools[key][platform]; ok {
+				continue
+			}
+
+			if l.Tools == nil {
+				l.Tools = map[string]map[string]Entry{}
+			}
+
+			if l.Tools[key] == nil {
+				l.Tools[key] = map[string]Entry{}
+			}
+
+			l.Tools[key][platform] = entry
+		}
+	}
+}
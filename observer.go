@@ -0,0 +1,54 @@
+package toolfetcher
+
+import "github.com/RobinThrift/toolfetcher/internal/installer"
+
+// Observer is notified about the progress of installing tools, e.g. to render progress bars.
+// When tools are fetched concurrently, its methods are called from multiple goroutines.
+// Embed NopObserver to only implement some of the methods.
+//
+// Resolve, download and extract events are only sent by the built-in installers.
+type Observer interface {
+	// OnResolve is called with the URL or module path a tool is installed from, before it is downloaded.
+	OnResolve(tool *Tool, url string)
+
+	// OnDownloadProgress is called while downloading with the number of bytes downloaded so far and
+	// the total size, which is -1 if unknown. A resumed download starts at the bytes downloaded before.
+	OnDownloadProgress(tool *Tool, downloaded int64, total int64)
+
+	// OnExtract is called once a download is complete, before it is unpacked.
+	OnExtract(tool *Tool)
+
+	// OnLink is called after a tool was linked into the bin dir at linkPath.
+	OnLink(tool *Tool, linkPath string)
+
+	// OnTest is called after running a tool's test command with the error it failed with, if any.
+	OnTest(tool *Tool, err error)
+}
+
+// NopObserver ignores all events.
+type NopObserver struct{}
+
+func (NopObserver) OnResolve(*Tool, string)                {}
+func (NopObserver) OnDownloadProgress(*Tool, int64, int64) {}
+func (NopObserver) OnExtract(*Tool)                        {}
+func (NopObserver) OnLink(*Tool, string)                   {}
+func (NopObserver) OnTest(*Tool, error)                    {}
+
+// observedDownloader reports the events of dl to the observer of tool.
+func observedDownloader(dl installer.Downloader, tool *Tool) installer.Downloader {
+	observer := tool.observe()
+
+	dl.OnResolve = func(url string) {
+		observer.OnResolve(tool, url)
+	}
+
+	dl.OnProgress = func(downloaded int64, total int64) {
+		observer.OnDownloadProgress(tool, downloaded, total)
+	}
+
+	dl.OnExtract = func() {
+		observer.OnExtract(tool)
+	}
+
+	return dl
+}
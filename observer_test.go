@@ -0,0 +1,102 @@
+package toolfetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_Observer(t *testing.T) {
+	archive := newTestTarGz(t, "tool", "#!/bin/sh\necho v1\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	cwd := t.TempDir()
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: github-releases://example/tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	observer := &recordingObserver{}
+	fetcher := &ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Observer:    observer,
+		Recipes: []recipes.Recipe{{
+			Name: "tool",
+			Src: recipes.Source{
+				Type:        recipes.SourceTypeBinDownload,
+				URLTemplate: srv.URL + "/tool-{{ .Version }}.tar.gz",
+			},
+			Test: []string{"--version"},
+		}},
+	}
+
+	err = fetcher.Fetch(context.Background(), "tool")
+	require.NoError(t, err)
+
+	size := strconv.Itoa(len(archive))
+	assert.Equal(t, []string{
+		"resolve tool " + srv.URL + "/tool-1.0.0.tar.gz",
+		"progress tool " + size + "/" + size,
+		"extract tool",
+		"test tool <nil>",
+		"link tool " + path.Join(cwd, ".bin", "tool"),
+	}, observer.events)
+
+	t.Run("Up To Date", func(t *testing.T) {
+		observer.events = nil
+
+		err = fetcher.Fetch(context.Background(), "tool")
+		require.NoError(t, err)
+
+		assert.Empty(t, observer.events)
+	})
+}
+
+// recordingObserver records all events, except for intermediate download progress.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) OnResolve(tool *Tool, url string) {
+	o.record("resolve " + tool.Name + " " + url)
+}
+
+func (o *recordingObserver) OnDownloadProgress(tool *Tool, downloaded int64, total int64) {
+	if downloaded == total {
+		o.record("progress " + tool.Name + " " + strconv.FormatInt(downloaded, 10) + "/" + strconv.FormatInt(total, 10))
+	}
+}
+
+func (o *recordingObserver) OnExtract(tool *Tool) {
+	o.record("extract " + tool.Name)
+}
+
+func (o *recordingObserver) OnLink(tool *Tool, linkPath string) {
+	o.record("link " + tool.Name + " " + linkPath)
+}
+
+func (o *recordingObserver) OnTest(tool *Tool, err error) {
+	o.record(fmt.Sprint("test ", tool.Name, " ", err))
+}
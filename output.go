@@ -0,0 +1,16 @@
+package toolfetcher
+
+import (
+	"bytes"
+	"io"
+)
+
+type bufferedOutput struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+func (o *bufferedOutput) flush(stdout io.Writer, stderr io.Writer) {
+	_, _ = io.Copy(stdout, &o.stdout)
+	_, _ = io.Copy(stderr, &o.stderr)
+}
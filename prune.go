@@ -0,0 +1,379 @@
+package toolfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobinThrift/toolfetcher/internal/fs"
+)
+
+// staleStagingAge is the age after which leftovers of interrupted installs are removed from the staging directory.
+const staleStagingAge = 24 * time.Hour
+
+type PruneOptions struct {
+	// KeepVersions keeps the given number of unreferenced versions of each tool, highest versions first.
+	KeepVersions int
+
+	// KeepUsedWithin keeps unreferenced store entries that were installed or linked within the given duration.
+	// Required when pruning a shared store, as other projects can't be asked which tools they use.
+	KeepUsedWithin time.Duration
+
+	// DryRun only reports what would be removed.
+	DryRun bool
+}
+
+type PruneReport struct {
+	// Removed lists the removed store entries, relative to the store dir.
+	Removed []string
+
+	// Kept lists the unreferenced store entries kept because of the retention options, relative to the store dir.
+	Kept []string
+
+	// Skipped lists the store entries that should be removed, but are in use by a running fetch.
+	Skipped []string
+}
+
+type storeEntry struct {
+	// path is relative to the store dir.
+	path     string
+	name     string
+	version  string
+	lastUsed time.Time
+}
+
+// Prune removes the entries from the store that are not referenced by the version file and not kept
+// by opts, as well as leftovers of interrupted installs.
+func (tf *ToolFetcher) Prune(_ context.Context, opts PruneOptions) (*PruneReport, error) {
+	run, err := tf.newRun()
+	if err != nil {
+		return nil, err
+	}
+
+	if tf.SharedStore && opts.KeepUsedWithin <= 0 {
+		return nil, ErrPruneSharedStore
+	}
+
+	referenced := map[string]bool{}
+	for name := range run.entries {
+		tool, err := run.tool(name)
+		if err != nil {
+			return nil, err
+		}
+
+		referenced[tool.StoreDir()] = true
+	}
+
+	entries, err := listStore(tf.StoreDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PruneReport{}
+	for _, entry := range selectPrunable(entries, referenced, opts, time.Now()) {
+		if !entry.prune {
+			report.Kept = append(report.Kept, entry.path)
+			continue
+		}
+
+		if opts.DryRun {
+			report.Removed = append(report.Removed, entry.path)
+			continue
+		}
+
+		removed, err := removeStoreEntry(tf.StoreDir, entry.path)
+		if err != nil {
+			return report, err
+		}
+
+		if !removed {
+			report.Skipped = append(report.Skipped, entry.path)
+			continue
+		}
+
+		report.Removed = append(report.Removed, entry.path)
+	}
+
+	staged, err := pruneStaging(tf.StoreDir, opts.DryRun, time.Now())
+	report.Removed = append(report.Removed, staged...)
+
+	return report, err
+}
+
+type pruneCandidate struct {
+	storeEntry
+	prune bool
+}
+
+// selectPrunable returns the unreferenced entries, sorted by path, and whether they should be removed.
+func selectPrunable(entries []storeEntry, referenced map[string]bool, opts PruneOptions, now time.Time) []pruneCandidate {
+	versions := map[string][]string{}
+	for _, entry := range entries {
+		if !referenced[entry.path] {
+			versions[entry.name] = append(versions[entry.name], entry.version)
+		}
+	}
+
+	keptVersions := map[string]bool{}
+	for name, vs := range versions {
+		sort.Slice(vs, func(i, j int) bool { return compareVersions(vs[i], vs[j]) > 0 })
+
+		kept := 0
+		for i, v := range vs {
+			if kept >= opts.KeepVersions {
+				break
+			}
+
+			// multiple entries (platforms, sources) of a version count as one
+			if i > 0 && vs[i-1] == v {
+				continue
+			}
+
+			keptVersions[name+"@"+v] = true
+			kept++
+		}
+	}
+
+	var candidates []pruneCandidate
+	for _, entry := range entries {
+		if referenced[entry.path] {
+			continue
+		}
+
+		usedRecently := opts.KeepUsedWithin > 0 && now.Sub(entry.lastUsed) < opts.KeepUsedWithin
+
+		candidates = append(candidates, pruneCandidate{
+			storeEntry: entry,
+			prune:      !usedRecently && !keptVersions[entry.name+"@"+entry.version],
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].path < candidates[j].path })
+
+	return candidates
+}
+
+// listStore returns all entries in the store laid out as name/version/GOOS_GOARCH/hash, and
+// entries in the name_version layout of earlier releases.
+func listStore(storeDir string) ([]storeEntry, error) {
+	names, err := readStoreDir(storeDir, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []storeEntry
+	for _, name := range names {
+		if strings.HasPrefix(name.Name(), ".") {
+			continue
+		}
+
+		if isLegacyStoreEntry(storeDir, name) {
+			entry, err := newStoreEntry(storeDir, name.Name())
+			if err != nil {
+				return nil, err
+			}
+
+			entry.name, entry.version, _ = cutLast(name.Name(), "_")
+			entries = append(entries, entry)
+
+			continue
+		}
+
+		// only directories are part of the layout above the entries themselves
+		versions, err := readStoreDir(storeDir, name.Name(), true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range versions {
+			platforms, err := readStoreDir(storeDir, path.Join(name.Name(), version.Name()), true)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, platform := range platforms {
+				hashes, err := readStoreDir(storeDir, path.Join(name.Name(), version.Name(), platform.Name()), false)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, hash := range hashes {
+					entry, err := newStoreEntry(storeDir, path.Join(name.Name(), version.Name(), platform.Name(), hash.Name()))
+					if err != nil {
+						return nil, err
+					}
+
+					entry.name = name.Name()
+					entry.version = version.Name()
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// readStoreDir lists storeDir/dir, only returning directories if dirsOnly is set.
+func readStoreDir(storeDir string, dir string, dirsOnly bool) ([]os.DirEntry, error) {
+	dirEntries, err := os.ReadDir(path.Join(storeDir, dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading store directory %s: %w", path.Join(storeDir, dir), err)
+	}
+
+	if !dirsOnly {
+		return dirEntries, nil
+	}
+
+	dirs := dirEntries[:0]
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			dirs = append(dirs, dirEntry)
+		}
+	}
+
+	return dirs, nil
+}
+
+// isLegacyStoreEntry reports whether a top level store entry is a name_version entry of earlier releases:
+// a binary installed by go install or a directory with an extracted archive, which, unlike a tool's
+// directory, contains files.
+func isLegacyStoreEntry(storeDir string, dirEntry os.DirEntry) bool {
+	if !dirEntry.IsDir() {
+		return true
+	}
+
+	children, err := os.ReadDir(path.Join(storeDir, dirEntry.Name()))
+	if err != nil {
+		return false
+	}
+
+	for _, child := range children {
+		if !child.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newStoreEntry(storeDir string, entryPath string) (storeEntry, error) {
+	info, err := os.Lstat(path.Join(storeDir, entryPath))
+	if err != nil {
+		return storeEntry{}, fmt.Errorf("error reading store entry %s: %w", entryPath, err)
+	}
+
+	return storeEntry{path: entryPath, lastUsed: info.ModTime()}, nil
+}
+
+// removeStoreEntry removes entryPath, its lock file and the directories above them that became empty.
+// Entries locked by a running fetch are not removed, which is reported by returning false.
+func removeStoreEntry(storeDir string, entryPath string) (bool, error) {
+	lock, err := fs.TryLock(storeLockPath(storeDir, entryPath))
+	if err != nil || lock == nil {
+		return false, err
+	}
+
+	err = os.RemoveAll(path.Join(storeDir, entryPath))
+	if err != nil {
+		return false, errors.Join(fmt.Errorf("error removing %s from store: %w", entryPath, err), lock.Unlock())
+	}
+
+	removeEmptyParents(storeDir, entryPath)
+
+	err = lock.UnlockAndRemove()
+	if err != nil {
+		return true, err
+	}
+
+	removeEmptyParents(path.Join(storeDir, locksDirName), entryPath)
+
+	return true, nil
+}
+
+// removeEmptyParents removes the directories between root and rel that are empty.
+func removeEmptyParents(root string, rel string) {
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		// fails for non-empty directories, which is what stops the loop
+		if os.Remove(path.Join(root, dir)) != nil {
+			break
+		}
+	}
+}
+
+// pruneStaging removes leftovers of interrupted installs. Recent ones are kept as they may belong to a running install.
+func pruneStaging(storeDir string, dryRun bool, now time.Time) ([]string, error) {
+	stagingRoot := path.Join(storeDir, stagingDirName)
+
+	dirEntries, err := os.ReadDir(stagingRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading staging directory %s: %w", stagingRoot, err)
+	}
+
+	var removed []string
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil || now.Sub(info.ModTime()) < staleStagingAge {
+			continue
+		}
+
+		if !dryRun {
+			err = os.RemoveAll(path.Join(stagingRoot, dirEntry.Name()))
+			if err != nil {
+				return removed, fmt.Errorf("error removing %s: %w", path.Join(stagingRoot, dirEntry.Name()), err)
+			}
+		}
+
+		removed = append(removed, path.Join(stagingDirName, dirEntry.Name()))
+	}
+
+	return removed, nil
+}
+
+// compareVersions orders versions by their dot separated components, numerically where possible,
+// so that 1.10.0 is higher than 1.9.0. A leading "v" is ignored.
+func compareVersions(a string, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+
+	return len(as) - len(bs)
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,162 @@
+package toolfetcher
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/RobinThrift/toolfetcher/internal/fs"
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_Prune(t *testing.T) {
+	platform := currentPlatformDir()
+	now := time.Now()
+
+	tt := []struct {
+		name    string
+		opts    PruneOptions
+		locked  []string
+		removed []string
+		kept    []string
+		skipped []string
+	}{
+		{
+			name:    "Unreferenced",
+			removed: []string{"foo/1.10.0/" + platform + "/abc", "foo/1.9.0/" + platform + "/abc", "foo/2.0.0/" + platform + "/abc", "foo_0.1.0", ".staging/foo_1.0.0-stale"},
+		},
+		{
+			name:    "Keep Versions",
+			opts:    PruneOptions{KeepVersions: 2},
+			removed: []string{"foo/1.9.0/" + platform + "/abc", "foo_0.1.0", ".staging/foo_1.0.0-stale"},
+			kept:    []string{"foo/1.10.0/" + platform + "/abc", "foo/2.0.0/" + platform + "/abc"},
+		},
+		{
+			name:    "Keep Used Within",
+			opts:    PruneOptions{KeepUsedWithin: time.Hour},
+			removed: []string{"foo/1.10.0/" + platform + "/abc", "foo/1.9.0/" + platform + "/abc", "foo_0.1.0", ".staging/foo_1.0.0-stale"},
+			kept:    []string{"foo/2.0.0/" + platform + "/abc"},
+		},
+		{
+			name:    "Locked",
+			locked:  []string{"foo/1.9.0/" + platform + "/abc"},
+			removed: []string{"foo/1.10.0/" + platform + "/abc", "foo/2.0.0/" + platform + "/abc", "foo_0.1.0", ".staging/foo_1.0.0-stale"},
+			skipped: []string{"foo/1.9.0/" + platform + "/abc"},
+		},
+		{
+			name:    "Dry Run",
+			opts:    PruneOptions{DryRun: true, KeepVersions: 1},
+			removed: []string{"foo/1.10.0/" + platform + "/abc", "foo/1.9.0/" + platform + "/abc", "foo_0.1.0", ".staging/foo_1.0.0-stale"},
+			kept:    []string{"foo/2.0.0/" + platform + "/abc"},
+		},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			cwd := t.TempDir()
+			storeDir := path.Join(cwd, ".bin", ".store")
+
+			toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+			err := os.WriteFile(toolfilePath, []byte("foo: script://foo@3.0.0\n"), 0o644)
+			require.NoError(t, err)
+
+			fetcher := ToolFetcher{
+				VersionFile: toolfilePath,
+				BinDir:      path.Join(cwd, ".bin"),
+				Recipes:     []recipes.Recipe{{Name: "foo", Src: recipes.Source{Type: "script"}}},
+			}
+
+			fetcher.RegisterInstaller("script", scriptInstaller("#!/bin/sh\n", nil))
+
+			require.NoError(t, fetcher.FetchAll(context.Background()))
+
+			writeStoreEntry(t, storeDir, "foo/1.9.0/"+platform+"/abc/foo", now.Add(-48*time.Hour))
+			writeStoreEntry(t, storeDir, "foo/1.10.0/"+platform+"/abc/foo", now.Add(-48*time.Hour))
+			writeStoreEntry(t, storeDir, "foo/2.0.0/"+platform+"/abc/foo", now.Add(-time.Minute))
+			writeStoreEntry(t, storeDir, "foo_0.1.0", now.Add(-48*time.Hour))
+			writeStoreEntry(t, storeDir, ".staging/foo_1.0.0-stale/foo", now.Add(-48*time.Hour))
+			writeStoreEntry(t, storeDir, ".staging/foo_1.0.0-running/foo", now)
+
+			for _, entry := range []string{"foo/1.9.0/" + platform + "/abc", "foo/1.10.0/" + platform + "/abc", "foo/2.0.0/" + platform + "/abc"} {
+				lock, err := fs.Lock(context.Background(), storeLockPath(storeDir, entry))
+				require.NoError(t, err)
+				require.NoError(t, lock.Unlock())
+			}
+
+			for _, entry := range tt.locked {
+				lock, err := fs.Lock(context.Background(), storeLockPath(storeDir, entry))
+				require.NoError(t, err)
+				t.Cleanup(func() { _ = lock.Unlock() })
+			}
+
+			report, err := fetcher.Prune(context.Background(), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.removed, report.Removed)
+			assert.Equal(t, tt.kept, report.Kept)
+			assert.Equal(t, tt.skipped, report.Skipped)
+
+			for _, entry := range tt.skipped {
+				assert.DirExists(t, path.Join(storeDir, entry))
+			}
+
+			for _, entry := range report.Removed {
+				_, err := os.Lstat(path.Join(storeDir, entry))
+				if tt.opts.DryRun {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, os.ErrNotExist)
+					assert.NoFileExists(t, storeLockPath(storeDir, entry))
+				}
+			}
+
+			for _, entry := range tt.kept {
+				assert.DirExists(t, path.Join(storeDir, entry))
+			}
+
+			assert.DirExists(t, path.Join(storeDir, ".staging/foo_1.0.0-running"))
+			assert.FileExists(t, path.Join(cwd, ".bin", "foo"))
+			require.NoError(t, fetcher.Check(context.Background()))
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tt := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.0.0", b: "1.0.0", expected: 0},
+		{a: "1.10.0", b: "1.9.0", expected: 1},
+		{a: "v2.0.0", b: "1.61.0", expected: 1},
+		{a: "1.0", b: "1.0.1", expected: -1},
+		{a: "1.0.0-rc1", b: "1.0.0-rc2", expected: -1},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			actual := compareVersions(tt.a, tt.b)
+			switch {
+			case tt.expected < 0:
+				assert.Negative(t, actual)
+			case tt.expected > 0:
+				assert.Positive(t, actual)
+			default:
+				assert.Zero(t, actual)
+			}
+		})
+	}
+}
+
+func writeStoreEntry(t *testing.T, storeDir string, entry string, modTime time.Time) {
+	t.Helper()
+
+	filePath := path.Join(storeDir, entry)
+	require.NoError(t, os.MkdirAll(path.Dir(filePath), 0o755))
+	require.NoError(t, os.WriteFile(filePath, []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+	require.NoError(t, os.Chtimes(path.Dir(filePath), modTime, modTime))
+}
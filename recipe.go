@@ -0,0 +1,32 @@
+package toolfetcher
+
+import (
+	"fmt"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/RobinThrift/toolfetcher/toolfile"
+)
+
+// defaultRecipe derives a recipe from the source in the version file for tools without an explicit recipe.
+func defaultRecipe(entry toolfile.Entry) (*recipes.Recipe, error) {
+	switch entry.Scheme {
+	case toolfile.SchemeGo:
+		return &recipes.Recipe{
+			Name: entry.Name,
+			Src: recipes.Source{
+				Type:        recipes.SourceTypeGoInstall,
+				URLTemplate: entry.Location,
+			},
+		}, nil
+	case toolfile.SchemeGitHubReleases:
+		return &recipes.Recipe{
+			Name: entry.Name,
+			Src: recipes.Source{
+				Type: recipes.SourceTypeGitHubRelease,
+				Repo: entry.Location,
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w '%s': no recipe found and no default recipe for source '%s://%s'", ErrUnknownTool, entry.Name, entry.Scheme, entry.Location)
+}
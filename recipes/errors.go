@@ -0,0 +1,8 @@
+package recipes
+
+import (
+	"errors"
+)
+
+var ErrParsingRecipeFile = errors.New("error parsing recipe file")
+var ErrInvalidRecipe = errors.New("invalid recipe")
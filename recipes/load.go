@@ -0,0 +1,247 @@
+package recipes
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads recipes from a YAML or JSON file, see [Load].
+func LoadFile(filepath string) ([]Recipe, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening recipe file %s: %w", filepath, err)
+	}
+	defer file.Close()
+
+	recipes, err := Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath, err)
+	}
+
+	return recipes, nil
+}
+
+// Load reads recipes from YAML or JSON in the form of:
+//
+//	recipes:
+//	  - name: git-cliff
+//	    src:
+//	      type: bindownload
+//	      url_template: https://github.com/orhun/git-cliff/releases/download/v{{ .Version }}/git-cliff-{{ .Version }}-{{ .Arch }}-{{ .OS }}.tar.gz
+//	    os: { darwin: apple-darwin, linux: unknown-linux-gnu }
+//	    arch: { arm64: aarch64, amd64: x86_64 }
+//	    test: [--version]
+//
+// All recipes are validated and the returned error lists every invalid field with its line number.
+func Load(r io.Reader) ([]Recipe, error) {
+	var doc yaml.Node
+
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return []Recipe{}, nil
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrParsingRecipeFile, err)
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w on line %d: expected a mapping with a 'recipes' key", ErrParsingRecipeFile, root.Line)
+	}
+
+	var recipeNodes *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "recipes" {
+			return nil, fmt.Errorf("%w on line %d: unknown key '%s'", ErrParsingRecipeFile, key.Line, key.Value)
+		}
+
+		recipeNodes = value
+	}
+
+	if recipeNodes == nil {
+		return []Recipe{}, nil
+	}
+
+	if recipeNodes.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w on line %d: 'recipes' must be a list", ErrParsingRecipeFile, recipeNodes.Line)
+	}
+
+	recipes := make([]Recipe, 0, len(recipeNodes.Content))
+	seen := map[string]int{}
+
+	var errs []error
+	for _, node := range recipeNodes.Content {
+		recipe, err := loadRecipe(node)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if line, ok := seen[recipe.Name]; ok {
+			errs = append(errs, fmt.Errorf("%w on line %d: duplicate recipe '%s', first defined on line %d", ErrInvalidRecipe, node.Line, recipe.Name, line))
+			continue
+		}
+
+		seen[recipe.Name] = node.Line
+		recipes = append(recipes, recipe)
+	}
+
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return recipes, nil
+}
+
+func loadRecipe(node *yaml.Node) (Recipe, error) {
+	var recipe Recipe
+
+	if node.Kind != yaml.MappingNode {
+		return recipe, fmt.Errorf("%w on line %d: expected a mapping", ErrInvalidRecipe, node.Line)
+	}
+
+	errs := checkKeys(node, reflect.TypeOf(recipe))
+	if src := mappingValue(node, "src"); src != nil {
+		if src.Kind == yaml.MappingNode {
+			errs = append(errs, checkKeys(src, reflect.TypeOf(recipe.Src))...)
+		}
+	}
+
+	if len(errs) != 0 {
+		return recipe, errors.Join(errs...)
+	}
+
+	err := node.Decode(&recipe)
+	if err != nil {
+		return recipe, fmt.Errorf("%w on line %d: %w", ErrInvalidRecipe, node.Line, err)
+	}
+
+	return recipe, validateRecipe(&recipe, node)
+}
+
+func validateRecipe(recipe *Recipe, node *yaml.Node) error {
+	var errs []error
+
+	invalid := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w on line %d: %s", ErrInvalidRecipe, fieldLine(node, field), fmt.Sprintf(format, args...)))
+	}
+
+	if recipe.Name == "" {
+		invalid("name", "missing name")
+	}
+
+	switch recipe.Src.Type {
+	case "":
+		invalid("src", "recipe '%s': missing src.type", recipe.Name)
+	case SourceTypeGoInstall, SourceTypeBinDownload:
+		if recipe.Src.URLTemplate == "" {
+			invalid("src", "recipe '%s': missing src.url_template", recipe.Name)
+		}
+	case SourceTypeGitHubRelease:
+		if recipe.Src.URLTemplate == "" && recipe.Src.Repo == "" {
+			invalid("src", "recipe '%s': one of src.repo or src.url_template is required", recipe.Name)
+		}
+	}
+
+	if _, err := template.New("").Parse(recipe.Src.URLTemplate); err != nil {
+		invalid("src.url_template", "recipe '%s': invalid src.url_template: %v", recipe.Name, err)
+	}
+
+	if _, err := template.New("").Parse(recipe.Src.ChecksumURLTemplate); err != nil {
+		invalid("src.checksum_url_template", "recipe '%s': invalid src.checksum_url_template: %v", recipe.Name, err)
+	}
+
+	switch recipe.Src.ArchiveFormat {
+	case "", ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarXz, ArchiveFormatTarBz2, ArchiveFormatTarZst, ArchiveFormatGz, ArchiveFormatBinary:
+	default:
+		invalid("src.archive_format", "recipe '%s': unknown src.archive_format '%s'", recipe.Name, recipe.Src.ArchiveFormat)
+	}
+
+	if recipe.Src.StripComponents < 0 {
+		invalid("src.strip_components", "recipe '%s': src.strip_components must not be negative", recipe.Name)
+	}
+
+	for _, pattern := range recipe.Src.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			invalid("src.include", "recipe '%s': invalid src.include pattern '%s': %v", recipe.Name, pattern, err)
+		}
+	}
+
+	for platform, checksum := range recipe.Src.SHA256 {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || goos == "" || goarch == "" {
+			invalid("src.sha256."+platform, "recipe '%s': invalid platform '%s' in src.sha256, expected GOOS/GOARCH", recipe.Name, platform)
+		}
+
+		if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != 32 {
+			invalid("src.sha256."+platform, "recipe '%s': invalid SHA-256 digest for %s", recipe.Name, platform)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkKeys(node *yaml.Node, typ reflect.Type) []error {
+	known := make([]string, 0, typ.NumField())
+	for i := range typ.NumField() {
+		known = append(known, typ.Field(i).Tag.Get("yaml"))
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !slices.Contains(known, key.Value) {
+			errs = append(errs, fmt.Errorf("%w on line %d: unknown field '%s'", ErrInvalidRecipe, key.Line, key.Value))
+		}
+	}
+
+	return errs
+}
+
+// fieldLine returns the line of the deepest key found for a dot separated field path, e.g. "src.url_template".
+func fieldLine(node *yaml.Node, field string) int {
+	line := node.Line
+
+	for _, key := range strings.SplitN(field, ".", 3) {
+		keyNode, valueNode := mappingEntry(node, key)
+		if keyNode == nil {
+			break
+		}
+
+		line = keyNode.Line
+		node = valueNode
+	}
+
+	return line
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
+
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
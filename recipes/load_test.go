@@ -0,0 +1,150 @@
+package recipes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	tt := []struct {
+		name     string
+		contents string
+		err      error
+		errLines []string
+		recipes  []Recipe
+	}{
+		{name: "Empty File", contents: "", recipes: []Recipe{}},
+
+		{
+			name: "Valid YAML",
+			contents: `recipes:
+  - name: staticcheck
+    src:
+      type: goinstall
+      url_template: honnef.co/go/tools/cmd/staticcheck
+    test: [--version]
+
+  - name: git-cliff
+    src:
+      type: bindownload
+      url_template: https://github.com/orhun/git-cliff/releases/download/v{{ .Version }}/git-cliff-{{ .Version }}-{{ .Arch }}-{{ .OS }}.tar.gz
+      sha256:
+        linux/amd64: 0d4bbb3e4d3a7f6a16b5e0f8c3a1d2e7f9b8c6a5d4e3f2a1b0c9d8e7f6a5b4c3
+    os: { darwin: apple-darwin, linux: unknown-linux-gnu }
+    arch: { arm64: aarch64, amd64: x86_64 }
+    test: [--version]
+`,
+			recipes: []Recipe{
+				{
+					Name: "staticcheck",
+					Src:  Source{Type: SourceTypeGoInstall, URLTemplate: "honnef.co/go/tools/cmd/staticcheck"},
+					Test: []string{"--version"},
+				},
+				{
+					Name: "git-cliff",
+					Src: Source{
+						Type:        SourceTypeBinDownload,
+						URLTemplate: "https://github.com/orhun/git-cliff/releases/download/v{{ .Version }}/git-cliff-{{ .Version }}-{{ .Arch }}-{{ .OS }}.tar.gz",
+						SHA256:      map[string]string{"linux/amd64": "0d4bbb3e4d3a7f6a16b5e0f8c3a1d2e7f9b8c6a5d4e3f2a1b0c9d8e7f6a5b4c3"},
+					},
+					OS:   map[string]string{"darwin": "apple-darwin", "linux": "unknown-linux-gnu"},
+					Arch: map[string]string{"arm64": "aarch64", "amd64": "x86_64"},
+					Test: []string{"--version"},
+				},
+			},
+		},
+
+		{
+			name:     "Valid JSON",
+			contents: `{"recipes": [{"name": "gum", "src": {"type": "github-release", "repo": "charmbracelet/gum"}}]}`,
+			recipes: []Recipe{
+				{Name: "gum", Src: Source{Type: SourceTypeGitHubRelease, Repo: "charmbracelet/gum"}},
+			},
+		},
+
+		{
+			name: "Strip Components And Include",
+			contents: `recipes:
+  - name: protoc
+    src:
+      type: bindownload
+      url_template: https://example.com/protoc-{{ .Version }}.zip
+      strip_components: 1
+      include: [bin/protoc]
+
+  - name: tool
+    src:
+      type: bindownload
+      url_template: https://example.com/tool.zip
+      strip_components: -1
+      include: ["bin/[tool"]
+`,
+			err:      ErrInvalidRecipe,
+			errLines: []string{"line 13: recipe 'tool': src.strip_components must not be negative", "line 14: recipe 'tool': invalid src.include pattern"},
+		},
+
+		{
+			name:     "Invalid Syntax",
+			contents: "recipes:\n  - name: [\n",
+			err:      ErrParsingRecipeFile,
+		},
+
+		{
+			name:     "Unknown Top Level Key",
+			contents: "tools: []\n",
+			err:      ErrParsingRecipeFile,
+			errLines: []string{"line 1"},
+		},
+
+		{
+			name: "Invalid Recipes",
+			contents: `recipes:
+  - name: staticcheck
+    src:
+      type: goinstall
+      url: honnef.co/go/tools/cmd/staticcheck
+
+  - src:
+      type: bindownload
+      url_template: https://example.com/{{ .Version }
+
+  - name: tool
+    src:
+      type: goinstall
+      url_template: example.com/tool
+
+  - name: gum
+    src:
+      type: github-release
+      sha256:
+        linux: abc
+
+  - name: tool
+    src:
+      type: goinstall
+      url_template: example.com/tool
+`,
+			err:      ErrInvalidRecipe,
+			errLines: []string{"line 5: unknown field 'url'", "line 7: missing name", "line 9", "line 17: recipe 'gum': one of src.repo", "line 20", "line 22: duplicate recipe 'tool', first defined on line 11"},
+		},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			recipes, err := Load(strings.NewReader(tt.contents))
+			if tt.err == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.recipes, recipes)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Nil(t, recipes)
+			for _, line := range tt.errLines {
+				assert.ErrorContains(t, err, line)
+			}
+		})
+	}
+}
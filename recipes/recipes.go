@@ -1,14 +1,70 @@
 package recipes
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
 type Recipe struct {
-	Name string
+	Name string `yaml:"name"`
+
+	Src Source `yaml:"src"`
+
+	Test []string `yaml:"test"`
+
+	Arch map[string]string `yaml:"arch"`
+	OS   map[string]string `yaml:"os"`
+}
+
+// fingerprintVersion identifies the encoding hashed by Fingerprint. It must be changed whenever the encoding of
+// existing fields changes, which changes all fingerprints and therefore reinstalls all tools.
+const fingerprintVersion = "toolfetcher-recipe-v1"
+
+// Fingerprint is a digest of everything about the recipe that affects how the tool is installed or tested
+// on the current platform. Fields that are unset are left out of the digest, so that adding fields to
+// recipes doesn't change the fingerprints of existing recipes.
+func (r *Recipe) Fingerprint() string {
+	return r.fingerprint(runtime.GOOS, runtime.GOARCH)
+}
+
+func (r *Recipe) fingerprint(goos string, goarch string) string {
+	hash := sha256.New()
+
+	// every value is length prefixed, so that no two recipes share an encoding
+	field := func(name string, values ...string) {
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			return
+		}
+
+		fmt.Fprintf(hash, "%s %d\n", name, len(values))
+		for _, v := range values {
+			fmt.Fprintf(hash, "%d:%s\n", len(v), v)
+		}
+	}
+
+	field("version", fingerprintVersion)
+	field("name", r.Name)
+	field("src.type", string(r.Src.Type))
+	field("src.url_template", r.Src.URLTemplate)
+	field("src.bin_path", r.Src.BinPath)
+	field("src.repo", r.Src.Repo)
+	field("src.sha256", r.Src.SHA256[goos+"/"+goarch])
+	field("src.checksum_url_template", r.Src.ChecksumURLTemplate)
+	field("src.archive_format", string(r.Src.ArchiveFormat))
 
-	Src Source
+	if r.Src.StripComponents != 0 {
+		field("src.strip_components", strconv.Itoa(r.Src.StripComponents))
+	}
 
-	Test []string
+	field("src.include", r.Src.Include...)
+	field("test", r.Test...)
+	field("os", r.OS[goos])
+	field("arch", r.Arch[goarch])
 
-	Arch map[string]string
-	OS   map[string]string
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
 type SourceType string
@@ -16,10 +72,54 @@ type SourceType string
 const (
 	SourceTypeGoInstall   SourceType = "goinstall"
 	SourceTypeBinDownload SourceType = "bindownload"
+
+	// SourceTypeGitHubRelease downloads the release asset best matching the current platform.
+	// URLTemplate can be set to override the asset selection.
+	SourceTypeGitHubRelease SourceType = "github-release"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip    ArchiveFormat = "zip"
+	ArchiveFormatTar    ArchiveFormat = "tar"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarXz  ArchiveFormat = "tar.xz"
+	ArchiveFormatTarBz2 ArchiveFormat = "tar.bz2"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+
+	// ArchiveFormatGz is a single gzip compressed binary.
+	ArchiveFormatGz ArchiveFormat = "gz"
+
+	// ArchiveFormatBinary is an uncompressed binary. It is placed in the store under the recipe's name.
+	ArchiveFormatBinary ArchiveFormat = "binary"
 )
 
 type Source struct {
-	Type        SourceType
-	URLTemplate string
-	BinPath     string
+	Type        SourceType `yaml:"type"`
+	URLTemplate string     `yaml:"url_template"`
+	BinPath     string     `yaml:"bin_path"`
+
+	// Repo is the GitHub repository in the form of "owner/name", used by SourceTypeGitHubRelease.
+	Repo string `yaml:"repo"`
+
+	// SHA256 pins the expected SHA-256 digest of the downloaded archive per platform.
+	// Keys are in the form of "GOOS/GOARCH", e.g. "linux/amd64".
+	SHA256 map[string]string `yaml:"sha256"`
+
+	// ChecksumURLTemplate points to a checksums file published next to the archive, e.g. "checksums.txt".
+	// It is rendered with the same data as URLTemplate. Ignored if SHA256 has an entry for the current platform.
+	ChecksumURLTemplate string `yaml:"checksum_url_template"`
+
+	// ArchiveFormat overrides the detection of the downloaded archive's format.
+	ArchiveFormat ArchiveFormat `yaml:"archive_format"`
+
+	// StripComponents removes the given number of leading directories from archive entries, like tar's --strip-components.
+	// Entries with fewer path components are skipped.
+	StripComponents int `yaml:"strip_components"`
+
+	// Include limits extraction to entries matching one of the glob patterns (see [path.Match]).
+	// Patterns are matched against the entry path after StripComponents is applied, a pattern matching a
+	// directory includes everything below it. Empty includes all entries.
+	Include []string `yaml:"include"`
 }
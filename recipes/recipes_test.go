@@ -0,0 +1,85 @@
+package recipes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipe_Fingerprint(t *testing.T) {
+	recipe := Recipe{
+		Name: "git-cliff",
+		Src: Source{
+			Type:        SourceTypeBinDownload,
+			URLTemplate: "https://example.com/git-cliff-{{ .Version }}-{{ .Arch }}-{{ .OS }}.tar.gz",
+			SHA256:      map[string]string{"linux/amd64": "abc", "darwin/arm64": "def"},
+		},
+		OS:   map[string]string{"darwin": "apple-darwin", "linux": "unknown-linux-gnu"},
+		Test: []string{"--version"},
+	}
+
+	fingerprint := recipe.fingerprint("linux", "amd64")
+	assert.Len(t, fingerprint, 64)
+
+	// the encoding must only change along with fingerprintVersion
+	assert.Equal(t, "b14950461f224f4bfad9e1fa8136dcccccc7da4cfb162e886723dde136f8b8da", fingerprint)
+
+	tt := []struct {
+		name    string
+		change  func(r *Recipe)
+		changed bool
+	}{
+		{name: "Other Platform SHA256", change: func(r *Recipe) { r.Src.SHA256 = map[string]string{"linux/amd64": "abc", "darwin/arm64": "123"} }},
+		{name: "Other Platform OS", change: func(r *Recipe) { r.OS = map[string]string{"linux": "unknown-linux-gnu", "windows": "pc-windows-msvc"} }},
+		{name: "Other Platform Arch", change: func(r *Recipe) { r.Arch = map[string]string{"arm64": "aarch64"} }},
+		{name: "Empty Include", change: func(r *Recipe) { r.Src.Include = []string{} }},
+		{name: "Current Platform SHA256", change: func(r *Recipe) { r.Src.SHA256 = map[string]string{"linux/amd64": "123"} }, changed: true},
+		{name: "Current Platform Arch", change: func(r *Recipe) { r.Arch = map[string]string{"amd64": "x86_64"} }, changed: true},
+		{name: "Strip Components", change: func(r *Recipe) { r.Src.StripComponents = 1 }, changed: true},
+		{name: "Include", change: func(r *Recipe) { r.Src.Include = []string{"bin/*"} }, changed: true},
+		{name: "Test", change: func(r *Recipe) { r.Test = []string{"version"} }, changed: true},
+		{name: "Test Split Differently", change: func(r *Recipe) { r.Test = []string{"--ver", "sion"} }, changed: true},
+	}
+
+	for _, tt := range tt {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := recipe
+			tt.change(&changed)
+
+			if tt.changed {
+				assert.NotEqual(t, fingerprint, changed.fingerprint("linux", "amd64"))
+			} else {
+				assert.Equal(t, fingerprint, changed.fingerprint("linux", "amd64"))
+			}
+		})
+	}
+}
+
+func TestRecipe_Fingerprint_LoadedAndLiteral(t *testing.T) {
+	loaded, err := Load(strings.NewReader(`recipes:
+  - name: protoc
+    src:
+      type: bindownload
+      url_template: https://example.com/protoc-{{ .Version }}-{{ .OS }}-{{ .Arch }}.zip
+      bin_path: bin/protoc
+      include: []
+      sha256: {}
+    os: {}
+    test: []
+`))
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+
+	literal := Recipe{
+		Name: "protoc",
+		Src: Source{
+			Type:        SourceTypeBinDownload,
+			URLTemplate: "https://example.com/protoc-{{ .Version }}-{{ .OS }}-{{ .Arch }}.zip",
+			BinPath:     "bin/protoc",
+		},
+	}
+
+	assert.Equal(t, literal.Fingerprint(), loaded[0].Fingerprint())
+}
@@ -0,0 +1,168 @@
+package toolfetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+)
+
+// stateFileName is the file in the bin dir recording which tools are linked into it.
+const stateFileName = ".toolfetcher-state.json"
+
+// binDirState tracks the tools linked into the bin dir, so that Fetch can tell whether a link is up to date
+// without relying on how the symlink resolves.
+type binDirState struct {
+	mu       sync.Mutex
+	filepath string
+	file     stateFile
+	changed  map[string]bool
+}
+
+type stateFile struct {
+	Tools map[string]linkedTool `json:"tools"`
+}
+
+type linkedTool struct {
+	Version string `json:"version"`
+
+	// Fingerprint is the fingerprint of the recipe the tool was installed with, see [recipes.Recipe.Fingerprint].
+	Fingerprint string `json:"fingerprint"`
+
+	// BinarySHA256 is the digest of the linked binary.
+	BinarySHA256 string `json:"binary_sha256"`
+}
+
+func (tf *ToolFetcher) readBinDirState() (*binDirState, error) {
+	state := &binDirState{filepath: path.Join(tf.BinDir, stateFileName), changed: map[string]bool{}}
+
+	file, err := readStateFile(state.filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	state.file = file
+
+	return state, nil
+}
+
+func readStateFile(filepath string) (stateFile, error) {
+	file := stateFile{Tools: map[string]linkedTool{}}
+
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+
+	if err != nil {
+		return file, fmt.Errorf("error reading state file %s: %w", filepath, err)
+	}
+
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		// the state is only a cache of what is linked, a broken file causes a relink of all tools
+		return stateFile{Tools: map[string]linkedTool{}}, nil
+	}
+
+	if file.Tools == nil {
+		file.Tools = map[string]linkedTool{}
+	}
+
+	return file, nil
+}
+
+func (s *binDirState) get(name string) (linkedTool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	linked, ok := s.file.Tools[name]
+
+	return linked, ok
+}
+
+func (s *binDirState) set(name string, linked linkedTool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.file.Tools[name] = linked
+	s.changed[name] = true
+}
+
+// save writes the state file, keeping the entries written by other processes since it was read.
+func (s *binDirState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.changed) == 0 {
+		return nil
+	}
+
+	onDisk, err := readStateFile(s.filepath)
+	if err != nil {
+		return err
+	}
+
+	for name := range s.changed {
+		onDisk.Tools[name] = s.file.Tools[name]
+	}
+
+	encoded, err := json.MarshalIndent(onDisk, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state file %s: %w", s.filepath, err)
+	}
+
+	err = os.MkdirAll(path.Dir(s.filepath), 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %w", path.Dir(s.filepath), err)
+	}
+
+	err = os.WriteFile(s.filepath, append(encoded, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing state file %s: %w", s.filepath, err)
+	}
+
+	s.file = onDisk
+	s.changed = map[string]bool{}
+
+	return nil
+}
+
+// linkState describes how the tool linked into the bin dir relates to the tool that should be linked.
+type linkState int
+
+const (
+	// linkUpToDate means the expected version is linked and unchanged.
+	linkUpToDate linkState = iota
+
+	// linkOutdated means no link, a link to a different store entry or a different version or recipe was recorded.
+	linkOutdated
+
+	// linkTampered means the expected store entry is linked, but its binary was changed since it was linked.
+	linkTampered
+)
+
+// checkLink compares the tool linked into binDir with the state recorded when it was linked.
+// storeDigest is the digest of the tool's binary in the store, see [storeBinarySHA256].
+func checkLink(tool *Tool, linked linkedTool, recorded bool, binDir string, storeDir string, storeDigest string) linkState {
+	if !recorded || linked.Version != tool.Version || linked.Fingerprint != tool.Recipe.Fingerprint() {
+		return linkOutdated
+	}
+
+	linkTarget, err := os.Stat(path.Join(binDir, tool.Name))
+	if err != nil {
+		return linkOutdated
+	}
+
+	storeBin, err := os.Stat(path.Join(storeDir, tool.BinPath()))
+	if err != nil || !os.SameFile(linkTarget, storeBin) {
+		return linkOutdated
+	}
+
+	if storeDigest != linked.BinarySHA256 {
+		return linkTampered
+	}
+
+	return linkUpToDate
+}
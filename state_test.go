@@ -0,0 +1,130 @@
+package toolfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_BinDirState(t *testing.T) {
+	cwd := t.TempDir()
+	binDir := path.Join(cwd, ".bin")
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: script://tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	var installs atomic.Int32
+
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      binDir,
+		Recipes:     []recipes.Recipe{{Name: "tool", Src: recipes.Source{Type: "script"}}},
+	}
+
+	fetcher.RegisterInstaller("script", scriptInstaller("#!/bin/sh\necho tool\n", &installs))
+
+	ctx := context.Background()
+	tool := &Tool{Name: "tool", Version: "1.0.0", Recipe: &fetcher.Recipes[0]}
+	stored := func() *Tool { return storedTool(t, &fetcher, tool) }
+
+	readState := func() linkedTool {
+		data, err := os.ReadFile(path.Join(binDir, stateFileName))
+		require.NoError(t, err)
+
+		var state stateFile
+		require.NoError(t, json.Unmarshal(data, &state))
+
+		return state.Tools["tool"]
+	}
+
+	assertLinked := func() {
+		t.Helper()
+
+		linkTarget, err := os.Stat(path.Join(binDir, "tool"))
+		require.NoError(t, err)
+
+		storeBin, err := os.Stat(path.Join(binDir, ".store", stored().BinPath()))
+		require.NoError(t, err)
+
+		assert.True(t, os.SameFile(linkTarget, storeBin))
+	}
+
+	t.Run("Records State", func(t *testing.T) {
+		require.NoError(t, fetcher.FetchAll(ctx))
+		assertLinked()
+
+		state := readState()
+		assert.Equal(t, "1.0.0", state.Version)
+		assert.Equal(t, tool.Recipe.Fingerprint(), state.Fingerprint)
+		assert.NotEmpty(t, state.BinarySHA256)
+		assert.EqualValues(t, 1, installs.Load())
+	})
+
+	t.Run("Replaced Binary In Bin Dir", func(t *testing.T) {
+		require.NoError(t, os.Remove(path.Join(binDir, "tool")))
+		require.NoError(t, os.WriteFile(path.Join(binDir, "tool"), []byte("#!/bin/sh\necho other\n"), 0o755))
+
+		require.NoError(t, fetcher.FetchAll(ctx))
+		assertLinked()
+		assert.EqualValues(t, 1, installs.Load())
+	})
+
+	t.Run("Changed Recipe", func(t *testing.T) {
+		previous := path.Join(binDir, ".store", stored().StoreDir())
+		fetcher.Recipes[0].Test = []string{"--version"}
+
+		require.NoError(t, fetcher.FetchAll(ctx))
+		assertLinked()
+		assert.Equal(t, fetcher.Recipes[0].Fingerprint(), readState().Fingerprint)
+		assert.EqualValues(t, 2, installs.Load())
+		assert.NotEqual(t, previous, path.Join(binDir, ".store", stored().StoreDir()))
+	})
+
+	t.Run("Tampered Binary", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(binDir, ".store", stored().BinPath()), []byte("#!/bin/sh\necho tampered\n"), 0o755))
+
+		statuses, err := fetcher.Status(ctx)
+		require.NoError(t, err)
+		assert.False(t, statuses[0].Installed)
+
+		installsBefore := installs.Load()
+		require.NoError(t, fetcher.FetchAll(ctx))
+		assertLinked()
+		assert.Equal(t, installsBefore+1, installs.Load())
+
+		content, err := os.ReadFile(path.Join(binDir, "tool"))
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho tool\n", string(content))
+	})
+
+	t.Run("Relative Bin Dir", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(cwd))
+		t.Cleanup(func() { _ = os.Chdir(wd) })
+
+		relative := fetcher
+		relative.BinDir = ".bin"
+		relative.StoreDir = ""
+		relative.VersionFile = "TOOL_VERSIONS"
+
+		installsBefore := installs.Load()
+
+		statuses, err := relative.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, statuses[0].Installed)
+
+		require.NoError(t, os.Remove(path.Join(binDir, "tool")))
+		require.NoError(t, relative.FetchAll(ctx))
+		assertLinked()
+		assert.Equal(t, installsBefore, installs.Load())
+	})
+}
@@ -0,0 +1,97 @@
+package toolfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+)
+
+type ToolStatus struct {
+	Name    string
+	Version string
+	Source  recipes.SourceType
+
+	// BinPath is the path of the tool's symlink in the bin dir.
+	BinPath string
+
+	// Installed reports whether the tool is linked into the bin dir at the expected version.
+	Installed bool
+
+	// InStore reports whether the tool is present in the store at the expected version.
+	InStore bool
+
+	// Err is set if the tool's recipe could not be determined.
+	Err error
+}
+
+// Status reports the install status of every tool in the version file without installing anything.
+func (tf *ToolFetcher) Status(_ context.Context) ([]ToolStatus, error) {
+	run, err := tf.newRun()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ToolStatus, 0, len(run.entries))
+	for name, entry := range run.entries {
+		status := ToolStatus{
+			Name:    name,
+			Version: entry.Version,
+			BinPath: path.Join(tf.BinDir, name),
+		}
+
+		tool, err := run.tool(name)
+		if err != nil {
+			status.Err = err
+			statuses = append(statuses, status)
+			continue
+		}
+
+		status.Source = tool.Recipe.Src.Type
+
+		linked, recorded := run.state.get(name)
+
+		status.InStore, err = toolBinExistsInStore(tool, tf.StoreDir)
+		if err != nil {
+			return nil, err
+		}
+
+		storeDigest, err := storeBinarySHA256(tool, tf.StoreDir)
+		if err != nil {
+			return nil, err
+		}
+
+		status.Installed = checkLink(tool, linked, recorded, tf.BinDir, tf.StoreDir, storeDigest) == linkUpToDate
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses, nil
+}
+
+// Check returns an error naming every tool in the version file that is not installed at the expected version.
+func (tf *ToolFetcher) Check(ctx context.Context) error {
+	statuses, err := tf.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, status := range statuses {
+		switch {
+		case status.Err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", status.Name, status.Err))
+		case !status.Installed:
+			errs = append(errs, fmt.Errorf("%s: %w at version %s", status.Name, ErrNotInstalled, status.Version))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package toolfetcher
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/RobinThrift/toolfetcher/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolFetcher_StatusCheckPrune(t *testing.T) {
+	cwd := t.TempDir()
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	writeToolFile := func(content string) {
+		err := os.WriteFile(toolfilePath, []byte(content), 0o644)
+		require.NoError(t, err)
+	}
+
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Recipes: []recipes.Recipe{
+			{Name: "foo", Src: recipes.Source{Type: "script"}},
+			{Name: "bar", Src: recipes.Source{Type: "script"}},
+		},
+	}
+
+	fetcher.RegisterInstaller("script", scriptInstaller("#!/bin/sh\n", nil))
+
+	ctx := context.Background()
+
+	writeToolFile("foo: script://foo@1.0.0\nbar: script://bar@1.0.0\n")
+
+	err := fetcher.Fetch(ctx, "foo")
+	require.NoError(t, err)
+
+	statuses, err := fetcher.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ToolStatus{
+		{Name: "bar", Version: "1.0.0", Source: "script", BinPath: path.Join(cwd, ".bin", "bar")},
+		{Name: "foo", Version: "1.0.0", Source: "script", BinPath: path.Join(cwd, ".bin", "foo"), Installed: true, InStore: true},
+	}, statuses)
+
+	err = fetcher.Check(ctx)
+	assert.ErrorIs(t, err, ErrNotInstalled)
+	assert.ErrorContains(t, err, "bar")
+
+	err = fetcher.FetchAll(ctx)
+	require.NoError(t, err)
+	require.NoError(t, fetcher.Check(ctx))
+
+	writeToolFile("foo: script://foo@2.0.0\nbar: script://bar@1.0.0\n")
+	require.NoError(t, fetcher.FetchAll(ctx))
+
+	// entry in the name_version layout of earlier releases
+	require.NoError(t, os.WriteFile(path.Join(cwd, ".bin", ".store", "foo_0.1.0"), []byte("#!/bin/sh\n"), 0o755))
+
+	report, err := fetcher.Prune(ctx, PruneOptions{})
+	require.NoError(t, err)
+
+	oldFoo := storedTool(t, &fetcher, &Tool{Name: "foo", Version: "1.0.0", Recipe: &fetcher.Recipes[0]})
+	assert.Equal(t, []string{oldFoo.StoreDir(), "foo_0.1.0"}, report.Removed)
+	assert.NoDirExists(t, path.Join(cwd, ".bin", ".store", "foo", "1.0.0"))
+
+	foo := storedTool(t, &fetcher, &Tool{Name: "foo", Version: "2.0.0", Recipe: &fetcher.Recipes[0]})
+	bar := storedTool(t, &fetcher, &Tool{Name: "bar", Version: "1.0.0", Recipe: &fetcher.Recipes[1]})
+	assert.FileExists(t, path.Join(cwd, ".bin", ".store", foo.StoreDir()))
+	assert.FileExists(t, path.Join(cwd, ".bin", ".store", bar.StoreDir()))
+}
@@ -3,13 +3,16 @@ package toolfetcher
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/RobinThrift/toolfetcher/internal/fs"
-	"github.com/RobinThrift/toolfetcher/internal/installer"
 	"github.com/RobinThrift/toolfetcher/recipes"
 )
 
@@ -17,14 +20,71 @@ type Tool struct {
 	Name    string
 	Version string
 	Recipe  *recipes.Recipe
+
+	Stdout io.Writer
+	Stderr io.Writer
+
+	observer Observer
+
+	// binarySHA256 is the digest of the binary recorded in the lock file, see StoreDir.
+	binarySHA256 string
 }
 
 func (t *Tool) VersionedName() string {
 	return t.Name + "@" + t.Version
 }
 
+// StoreDir is the tool's location relative to the store, laid out as name/version/GOOS_GOARCH/key.
+// The key is derived from the recipe's fingerprint, so that changing a recipe results in a fresh install,
+// and from the binary's digest recorded in the lock file, so that projects sharing a store but locking
+// different artifacts each get their own entry. Binaries built by go install depend on the local
+// Go toolchain and are only keyed by the recipe.
 func (t *Tool) StoreDir() string {
-	return t.Name + "_" + t.Version
+	key := t.Recipe.Fingerprint()[:16]
+	if t.binarySHA256 != "" {
+		key += "-" + strings.ToLower(t.binarySHA256[:min(len(t.binarySHA256), 16)])
+	}
+
+	return path.Join(t.Name, t.Version, currentPlatformDir(), key)
+}
+
+// adoptStoreEntry stores a tool that isn't locked yet under the most recently used entry installed with the
+// same recipe, e.g. by another project sharing the store, as long as its binary matches the digest it is stored under.
+// It returns the digest of the adopted binary, or an empty string if no entry was adopted.
+func adoptStoreEntry(t *Tool, storeDir string) (string, error) {
+	if t.Recipe.Src.Type == recipes.SourceTypeGoInstall {
+		return "", nil
+	}
+
+	entries, err := filepath.Glob(path.Join(storeDir, t.StoreDir()+"-*"))
+	if err != nil {
+		return "", fmt.Errorf("error looking up %s in the store: %w", t.VersionedName(), err)
+	}
+
+	var lastUsed time.Time
+	for _, entry := range entries {
+		info, err := os.Stat(entry)
+		if err != nil || info.ModTime().Before(lastUsed) {
+			continue
+		}
+
+		candidate := *t
+		_, candidate.binarySHA256, _ = strings.Cut(path.Base(entry), "-")
+
+		digest, err := fs.FileSHA256(path.Join(storeDir, candidate.BinPath()))
+		if err != nil || !strings.HasPrefix(digest, candidate.binarySHA256) {
+			continue
+		}
+
+		t.binarySHA256 = digest
+		lastUsed = info.ModTime()
+	}
+
+	return t.binarySHA256, nil
+}
+
+func currentPlatformDir() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
 }
 
 func (t *Tool) BinPath() string {
@@ -32,7 +92,7 @@ func (t *Tool) BinPath() string {
 		return path.Join(t.StoreDir(), t.Recipe.Src.BinPath)
 	}
 
-	if t.Recipe.Src.Type == recipes.SourceTypeBinDownload {
+	if t.Recipe.Src.Type == recipes.SourceTypeBinDownload || t.Recipe.Src.Type == recipes.SourceTypeGitHubRelease {
 		return path.Join(t.StoreDir(), t.Name)
 	}
 
@@ -40,25 +100,51 @@ func (t *Tool) BinPath() string {
 }
 
 func (t *Tool) ExecTest(ctx context.Context, localBinDir string) error {
+	return t.execTest(ctx, path.Join(localBinDir, t.Name))
+}
+
+func (t *Tool) execTest(ctx context.Context, binPath string) error {
 	if len(t.Recipe.Test) == 0 {
 		return nil
 	}
 
-	cmd := exec.CommandContext(ctx, path.Join(localBinDir, t.Name), t.Recipe.Test...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	cmd := exec.CommandContext(ctx, binPath, t.Recipe.Test...)
+	cmd.Stderr = t.stderr()
+	cmd.Stdout = t.stdout()
 	cmd.Stdin = os.Stdin
 
 	err := cmd.Run()
 	if err != nil {
-		return fmt.Errorf("error running %s %s: %v", t.Name, strings.Join(t.Recipe.Test, " "), err)
+		err = fmt.Errorf("error running %s %s: %v", t.Name, strings.Join(t.Recipe.Test, " "), err)
 	}
 
-	return nil
+	t.observe().OnTest(t, err)
+
+	return err
+}
+
+func (t *Tool) stdout() io.Writer {
+	if t.Stdout != nil {
+		return t.Stdout
+	}
+
+	return os.Stdout
 }
 
-func toolSymlinkExists(t *Tool, binDir string, storeDir string) (bool, error) {
-	return fs.SymlinkExists(t.BinPath(), t.StoreDir(), binDir, storeDir)
+func (t *Tool) stderr() io.Writer {
+	if t.Stderr != nil {
+		return t.Stderr
+	}
+
+	return os.Stderr
+}
+
+func (t *Tool) observe() Observer {
+	if t.observer != nil {
+		return t.observer
+	}
+
+	return NopObserver{}
 }
 
 func toolBinExistsInStore(t *Tool, storeDir string) (bool, error) {
@@ -70,17 +156,25 @@ func toolBinExistsInStore(t *Tool, storeDir string) (bool, error) {
 	return exists, nil
 }
 
-func symlinkTool(t *Tool, binDir string, storeDir string) error {
-	return fs.Symlink(t.Name, t.BinPath(), binDir, storeDir)
-}
+// storeBinarySHA256 returns the digest of the tool's binary in the store, or an empty string if it isn't stored.
+func storeBinarySHA256(t *Tool, storeDir string) (string, error) {
+	binPath := path.Join(storeDir, t.BinPath())
 
-func installTool(ctx context.Context, tool *Tool, storeDir string) error {
-	switch tool.Recipe.Src.Type {
-	case recipes.SourceTypeGoInstall:
-		return installer.InstallWithGoInstall(ctx, tool.Recipe, tool.Version, storeDir)
-	case recipes.SourceTypeBinDownload:
-		return installer.InstallFromBinDownload(ctx, tool.Recipe, tool.Version, storeDir)
+	exists, err := fs.FileExists(binPath)
+	if err != nil || !exists {
+		return "", err
 	}
 
-	return fmt.Errorf("error installing tool %s: unknown install method %s", tool.VersionedName(), tool.Recipe.Src.Type)
+	return fs.FileSHA256(binPath)
+}
+
+// touchStoreEntry records when a tool was last used in the modification time of its store entry,
+// which Prune uses for PruneOptions.KeepUsedWithin. This is best effort, e.g. a shared store may be read-only.
+func touchStoreEntry(t *Tool, storeDir string) {
+	now := time.Now()
+	_ = os.Chtimes(path.Join(storeDir, t.StoreDir()), now, now)
+}
+
+func symlinkTool(t *Tool, binDir string, storeDir string) error {
+	return fs.Symlink(t.Name, t.BinPath(), binDir, storeDir)
 }
@@ -2,10 +2,19 @@ package toolfetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/RobinThrift/toolfetcher/internal/fetch"
+	"github.com/RobinThrift/toolfetcher/internal/fs"
 	"github.com/RobinThrift/toolfetcher/recipes"
 	"github.com/RobinThrift/toolfetcher/toolfile"
 )
@@ -15,76 +24,502 @@ type ToolFetcher struct {
 	BinDir      string
 	StoreDir    string
 	Recipes     []recipes.Recipe
+
+	// SharedStore defaults StoreDir to a store in the user's cache directory (see [os.UserCacheDir])
+	// instead of BinDir/.store, so that tools are downloaded once and shared between projects.
+	SharedStore bool
+
+	// RecipeFile is an optional YAML or JSON file with additional recipes, see [recipes.LoadFile].
+	// Recipes in Recipes take precedence over recipes with the same name in RecipeFile.
+	RecipeFile string
+
+	// Installers maps source types to custom installers, overriding the built-in ones.
+	// See RegisterInstaller.
+	Installers map[recipes.SourceType]Installer
+
+	// LockFile records the resolved URLs and digests of installed tools.
+	// Defaults to VersionFile + ".lock".
+	LockFile string
+
+	// Frozen makes installs fail instead of adding new entries to the lock file.
+	Frozen bool
+
+	// Concurrency limits how many tools FetchAll and FetchTools install in parallel.
+	// Values below 1 are treated as 1.
+	Concurrency int
+
+	// HTTPClient is used for all downloads and API requests made by the built-in installers.
+	// Set it to configure timeouts, proxies or a custom transport. Defaults to [http.DefaultClient],
+	// which uses the proxy configured in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	HTTPClient *http.Client
+
+	// Retry configures how often failed requests are retried. The zero value uses [DefaultRetryPolicy].
+	Retry RetryPolicy
+
+	// Observer is notified about the progress of installs, e.g. download progress.
+	Observer Observer
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// stagingDirName is the directory inside the store in which tools are installed before being moved into place.
+const stagingDirName = ".staging"
+
+// locksDirName is the directory inside the store holding the per tool lock files.
+const locksDirName = ".locks"
+
+// storeLockPath is the lock file guarding the store entry at entryPath, relative to storeDir.
+// Entries of the same recipe share a lock, regardless of the binary they contain, see Tool.StoreDir.
+func storeLockPath(storeDir string, entryPath string) string {
+	if dir, key := path.Split(entryPath); strings.Count(entryPath, "/") == 3 {
+		key, _, _ = strings.Cut(key, "-")
+		entryPath = dir + key
+	}
+
+	return path.Join(storeDir, locksDirName, entryPath+".lock")
+}
+
+// binDirLockName is the lock file guarding the symlinks in the bin dir and the lock file.
+const binDirLockName = ".toolfetcher.lock"
+
+// fetchRun holds the state read once per call to Fetch, FetchAll or FetchTools.
+type fetchRun struct {
+	entries toolfile.Entries
+	recipes []recipes.Recipe
+	lock    *toolLock
+	state   *binDirState
 }
 
 func (tf *ToolFetcher) Fetch(ctx context.Context, toolname string) error {
+	run, err := tf.newRun()
+	if err != nil {
+		return err
+	}
+
+	tool, err := run.tool(toolname)
+	if err != nil {
+		return err
+	}
+
+	tool.Stdout = tf.stdout()
+	tool.Stderr = tf.stderr()
+	tool.observer = tf.Observer
+
+	err = tf.fetchTool(ctx, tool, run)
+
+	return errors.Join(err, tf.saveRun(ctx, run))
+}
+
+func (tf *ToolFetcher) FetchAll(ctx context.Context) error {
+	run, err := tf.newRun()
+	if err != nil {
+		return err
+	}
+
+	toolnames := make([]string, 0, len(run.entries))
+	for name := range run.entries {
+		toolnames = append(toolnames, name)
+	}
+
+	sort.Strings(toolnames)
+
+	err = tf.fetchTools(ctx, toolnames, run)
+
+	return errors.Join(err, tf.saveRun(ctx, run))
+}
+
+func (tf *ToolFetcher) FetchTools(ctx context.Context, toolnames []string) error {
+	run, err := tf.newRun()
+	if err != nil {
+		return err
+	}
+
+	err = tf.fetchTools(ctx, toolnames, run)
+
+	return errors.Join(err, tf.saveRun(ctx, run))
+}
+
+func (tf *ToolFetcher) fetchTools(ctx context.Context, toolnames []string, run *fetchRun) error {
+	concurrency := max(tf.Concurrency, 1)
+
+	var wg sync.WaitGroup
+	var outputMu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	errs := make([]error, len(toolnames))
+
+	for i, toolname := range toolnames {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			tool, err := run.tool(toolname)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", toolname, err)
+				return
+			}
+
+			tool.observer = tf.Observer
+
+			if concurrency == 1 {
+				tool.Stdout = tf.stdout()
+				tool.Stderr = tf.stderr()
+				err = tf.fetchTool(ctx, tool, run)
+			} else {
+				// buffer the output of each tool so that parallel installs don't interleave on the terminal
+				var output bufferedOutput
+				tool.Stdout = &output.stdout
+				tool.Stderr = &output.stderr
+
+				err = tf.fetchTool(ctx, tool, run)
+
+				outputMu.Lock()
+				output.flush(tf.stdout(), tf.stderr())
+				outputMu.Unlock()
+			}
+
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", toolname, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrFetchingTools, err)
+	}
+
+	return nil
+}
+
+func (tf *ToolFetcher) newRun() (*fetchRun, error) {
+	err := tf.setDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := tf.readVersionFile()
+	if err != nil {
+		return nil, err
+	}
+
+	allRecipes, err := tf.readRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := tf.readLockFile()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := tf.readBinDirState()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fetchRun{entries: entries, recipes: allRecipes, lock: lock, state: state}, nil
+}
+
+func (tf *ToolFetcher) setDefaults() error {
 	if tf.BinDir == "" {
 		tf.BinDir = ".bin"
 	}
 
-	if tf.StoreDir == "" {
+	if tf.StoreDir != "" {
+		return nil
+	}
+
+	if !tf.SharedStore {
 		tf.StoreDir = path.Join(tf.BinDir, ".store")
+		return nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return fmt.Errorf("error determining shared store directory: %w", err)
 	}
 
+	tf.StoreDir = filepath.ToSlash(filepath.Join(cacheDir, "toolfetcher", "store"))
+
+	return nil
+}
+
+// RetryPolicy configures the retries of requests failing with a transient error,
+// like connection errors or 5xx responses.
+type RetryPolicy = fetch.RetryPolicy
+
+// DefaultRetryPolicy is used when ToolFetcher.Retry is the zero value.
+var DefaultRetryPolicy = fetch.DefaultRetryPolicy
+
+func (tf *ToolFetcher) fetchClient() *fetch.Client {
+	return &fetch.Client{HTTPClient: tf.HTTPClient, Retry: tf.Retry}
+}
+
+func (tf *ToolFetcher) stdout() io.Writer {
+	if tf.Stdout != nil {
+		return tf.Stdout
+	}
+
+	return os.Stdout
+}
+
+func (tf *ToolFetcher) stderr() io.Writer {
+	if tf.Stderr != nil {
+		return tf.Stderr
+	}
+
+	return os.Stderr
+}
+
+func (tf *ToolFetcher) readVersionFile() (toolfile.Entries, error) {
 	versionFile, err := os.Open(tf.VersionFile)
 	if err != nil {
-		return fmt.Errorf("error opening version file %s: %w", tf.VersionFile, err)
+		return nil, fmt.Errorf("error opening version file %s: %w", tf.VersionFile, err)
 	}
 	defer versionFile.Close()
 
+	return toolfile.ParseToolFile(versionFile)
+}
+
+func (tf *ToolFetcher) readRecipes() ([]recipes.Recipe, error) {
+	if tf.RecipeFile == "" {
+		return tf.Recipes, nil
+	}
+
+	fromFile, err := recipes.LoadFile(tf.RecipeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]recipes.Recipe{}, tf.Recipes...), fromFile...), nil
+}
+
+func (run *fetchRun) tool(toolname string) (*Tool, error) {
+	entry, ok := run.entries[toolname]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownTool, toolname)
+	}
+
 	var recipe *recipes.Recipe
-	for i, r := range tf.Recipes {
+	for i, r := range run.recipes {
 		if r.Name == toolname {
-			recipe = &tf.Recipes[i]
+			recipe = &run.recipes[i]
 			break
 		}
 	}
 
 	if recipe == nil {
-		return fmt.Errorf("unknown tool '%s'", toolname)
+		var err error
+		recipe, err = defaultRecipe(entry)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	entries, err := toolfile.ParseToolFile(versionFile)
+	tool := &Tool{
+		Name:    toolname,
+		Version: entry.Version,
+		Recipe:  recipe,
+	}
+
+	tool.binarySHA256 = run.lock.lockedBinary(tool)
+
+	return tool, nil
+}
+
+func (tf *ToolFetcher) fetchTool(ctx context.Context, tool *Tool, run *fetchRun) (err error) {
+	// concurrent invocations wait here, the store is only checked after acquiring the lock
+	// so that they reuse the tool installed by the first one
+	storeLock, err := fs.Lock(ctx, storeLockPath(tf.StoreDir, tool.StoreDir()))
 	if err != nil {
 		return err
 	}
+	defer storeLock.Unlock()
 
-	entry, ok := entries[toolname]
-	if !ok {
-		return fmt.Errorf("unknown tool '%s'", toolname)
+	defer func() {
+		if err == nil {
+			touchStoreEntry(tool, tf.StoreDir)
+		}
+	}()
+
+	// the binary is hashed once for checking the link and the lock entry and for recording the link,
+	// adopting an entry already hashes it
+	var storeDigest string
+	if tool.binarySHA256 == "" {
+		storeDigest, err = adoptStoreEntry(tool, tf.StoreDir)
+		if err != nil {
+			return err
+		}
 	}
 
-	tool := &Tool{
-		Name:    toolname,
-		Version: entry.Version,
-		Recipe:  recipe,
+	if storeDigest == "" {
+		storeDigest, err = storeBinarySHA256(tool, tf.StoreDir)
+		if err != nil {
+			return err
+		}
 	}
 
-	exists, err := toolSymlinkExists(tool, tf.BinDir, tf.StoreDir)
+	linked, recorded := run.state.get(tool.Name)
+
+	link := checkLink(tool, linked, recorded, tf.BinDir, tf.StoreDir, storeDigest)
+
+	// a binary changed after it was linked can't be trusted, neither can one not matching the digest
+	// it is stored under
+	if storeDigest != "" && link != linkTampered {
+		err = run.lock.ensureEntry(ctx, tool, storeDigest, tf.urlResolverFor(tool))
+
+		switch {
+		case err == nil && link == linkUpToDate:
+			return nil
+		case err == nil:
+			err = tf.linkTool(ctx, tool, storeDigest, run.state)
+			if err != nil {
+				return err
+			}
+
+			return tool.ExecTest(ctx, tf.BinDir)
+		case !errors.Is(err, ErrLockMismatch):
+			return err
+		}
+	}
+
+	// installTool verifies the staged install against the lock file and runs the test on the staged binary
+	// before it replaces the store entry, so an install that doesn't match the lock leaves the entry intact
+	storeDigest, err = tf.installTool(ctx, tool, run.lock)
 	if err != nil {
 		return err
 	}
 
-	if exists {
-		return nil
+	return tf.linkTool(ctx, tool, storeDigest, run.state)
+}
+
+// linkTool symlinks the tool into the bin dir and records it in the bin dir state, along with digest,
+// the digest of its binary in the store.
+// Links point to absolute paths, so that they work regardless of how BinDir and StoreDir are specified.
+func (tf *ToolFetcher) linkTool(ctx context.Context, tool *Tool, digest string, state *binDirState) error {
+	storeDir, err := filepath.Abs(tf.StoreDir)
+	if err != nil {
+		return fmt.Errorf("error linking %s: %w", tool.VersionedName(), err)
 	}
 
-	inStore, err := toolBinExistsInStore(tool, tf.StoreDir)
+	binLock, err := fs.Lock(ctx, path.Join(tf.BinDir, binDirLockName))
 	if err != nil {
 		return err
 	}
+	defer binLock.Unlock()
 
-	if !inStore {
-		err = installTool(ctx, tool, tf.StoreDir)
-		if err != nil {
-			return err
-		}
+	err = symlinkTool(tool, tf.BinDir, filepath.ToSlash(storeDir))
+	if err != nil {
+		return err
 	}
 
-	err = symlinkTool(tool, tf.BinDir, tf.StoreDir)
+	tool.observe().OnLink(tool, path.Join(tf.BinDir, tool.Name))
+
+	state.set(tool.Name, linkedTool{
+		Version:      tool.Version,
+		Fingerprint:  tool.Recipe.Fingerprint(),
+		BinarySHA256: digest,
+	})
+
+	return nil
+}
+
+// saveRun writes the lock file and the bin dir state.
+func (tf *ToolFetcher) saveRun(ctx context.Context, run *fetchRun) error {
+	binLock, err := fs.Lock(ctx, path.Join(tf.BinDir, binDirLockName))
 	if err != nil {
 		return err
 	}
+	defer binLock.Unlock()
+
+	return errors.Join(run.lock.save(), run.state.save())
+}
+
+// installTool installs tool into a staging directory inside the store and only moves it into place
+// once it's verified and its test passed, so that an interrupted install never leaves a partial store entry.
+// It returns the digest of the installed binary.
+func (tf *ToolFetcher) installTool(ctx context.Context, tool *Tool, lock *toolLock) (string, error) {
+	installer, ok := tf.installerFor(tool.Recipe.Src.Type)
+	if !ok {
+		return "", fmt.Errorf("error installing tool %s: unknown install method %s", tool.VersionedName(), tool.Recipe.Src.Type)
+	}
+
+	stagingRoot := path.Join(tf.StoreDir, stagingDirName)
+
+	err := os.MkdirAll(stagingRoot, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("error creating staging directory %s: %w", stagingRoot, err)
+	}
+
+	stagingDir, err := os.MkdirTemp(stagingRoot, tool.Name+"_"+tool.Version+"-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating staging directory for %s: %w", tool.VersionedName(), err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	err = os.MkdirAll(path.Dir(path.Join(stagingDir, tool.StoreDir())), 0o755)
+	if err != nil {
+		return "", fmt.Errorf("error creating staging directory for %s: %w", tool.VersionedName(), err)
+	}
+
+	result, err := installer.Install(ctx, tool, stagingDir)
+	if err != nil {
+		return "", err
+	}
+
+	binarySHA256, err := lock.verifyInstall(tool, result, stagingDir)
+	if err != nil {
+		return "", err
+	}
+
+	err = tool.execTest(ctx, path.Join(stagingDir, tool.BinPath()))
+	if err != nil {
+		return "", err
+	}
+
+	staged := path.Join(stagingDir, tool.StoreDir())
+
+	// a tool that wasn't locked yet is stored under the digest of its binary from now on
+	if tool.Recipe.Src.Type != recipes.SourceTypeGoInstall {
+		tool.binarySHA256 = binarySHA256
+	}
+
+	storeEntry := path.Join(tf.StoreDir, tool.StoreDir())
+
+	// adoptStoreEntry skips entries whose binary doesn't match the digest they are stored under,
+	// which are replaced
+	err = os.RemoveAll(storeEntry)
+	if err != nil {
+		return "", fmt.Errorf("error removing modified store entry of %s: %w", tool.VersionedName(), err)
+	}
+
+	err = os.MkdirAll(path.Dir(storeEntry), 0o755)
+	if err != nil {
+		return "", fmt.Errorf("error creating store directory %s: %w", path.Dir(storeEntry), err)
+	}
+
+	err = os.Rename(staged, storeEntry)
+	if err != nil {
+		return "", fmt.Errorf("error moving %s into the store: %w", tool.VersionedName(), err)
+	}
+
+	return binarySHA256, nil
+}
+
+func (tf *ToolFetcher) urlResolverFor(tool *Tool) URLResolver {
+	installer, ok := tf.installerFor(tool.Recipe.Src.Type)
+	if !ok {
+		return nil
+	}
+
+	resolver, _ := installer.(URLResolver)
 
-	return tool.ExecTest(ctx, tf.BinDir)
+	return resolver
 }
@@ -1,13 +1,24 @@
 package toolfetcher
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/RobinThrift/toolfetcher/lockfile"
 	"github.com/RobinThrift/toolfetcher/recipes"
 	"github.com/RobinThrift/toolfetcher/toolfile"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -85,6 +96,49 @@ func TestToolFetcher_Fetch(t *testing.T) {
 
 }
 
+func TestToolFetcher_Fetch_StripComponents(t *testing.T) {
+	archive := newTestTarGz(t, "tool-1.0.0/tool", "#!/bin/sh\necho 1.0.0\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+
+	cwd := t.TempDir()
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("tool: github-releases://example/tool@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Recipes: []recipes.Recipe{{
+			Name: "tool",
+			Src: recipes.Source{
+				Type:            recipes.SourceTypeBinDownload,
+				URLTemplate:     srv.URL + "/tool-{{ .Version }}.tar.gz",
+				StripComponents: 1,
+			},
+			Test: []string{"--version"},
+		}},
+		Stdout: io.Discard,
+	}
+
+	err = fetcher.FetchAll(context.Background())
+	require.NoError(t, err)
+
+	// the binary is extracted to the top of the store entry instead of being linked from the nested directory
+	tool := storedTool(t, &fetcher, &Tool{Name: "tool", Version: "1.0.0", Recipe: &fetcher.Recipes[0]})
+	info, err := os.Lstat(path.Join(fetcher.StoreDir, tool.BinPath()))
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+	assert.NoDirExists(t, path.Join(fetcher.StoreDir, tool.StoreDir(), "tool-1.0.0"))
+
+	content, err := os.ReadFile(path.Join(cwd, ".bin", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\necho 1.0.0\n", string(content))
+}
+
 func readToolFile(t *testing.T, toolfilePath string) []string {
 	file, err := os.Open(toolfilePath)
 	if err != nil {
@@ -104,3 +158,223 @@ func readToolFile(t *testing.T, toolfilePath string) []string {
 
 	return toolnames
 }
+
+func TestToolFetcher_FetchAll_AggregatesErrors(t *testing.T) {
+	cwd := t.TempDir()
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("foo: npm://foo@1.0.0\nbar: npm://bar@2.0.0\n"), 0o644)
+	if err != nil {
+		require.NoError(t, err)
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("Concurrency %d", concurrency), func(t *testing.T) {
+			fetcher := ToolFetcher{
+				VersionFile: toolfilePath,
+				BinDir:      cwd,
+				Concurrency: concurrency,
+			}
+
+			err := fetcher.FetchAll(context.Background())
+			require.ErrorIs(t, err, ErrFetchingTools)
+			require.ErrorIs(t, err, ErrUnknownTool)
+			require.ErrorContains(t, err, "foo")
+			require.ErrorContains(t, err, "bar")
+		})
+	}
+}
+
+func TestToolFetcher_FetchAll_Concurrency(t *testing.T) {
+	const concurrency = 2
+
+	cwd := t.TempDir()
+
+	toolnames := []string{"a", "b", "c", "d", "e", "f"}
+
+	var versions strings.Builder
+	var toolRecipes []recipes.Recipe
+	for _, name := range toolnames {
+		fmt.Fprintf(&versions, "%s: script://%s@1.0.0\n", name, name)
+		toolRecipes = append(toolRecipes, recipes.Recipe{Name: name, Src: recipes.Source{Type: "script"}})
+	}
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte(versions.String()), 0o644)
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	fetcher := ToolFetcher{
+		VersionFile: toolfilePath,
+		BinDir:      path.Join(cwd, ".bin"),
+		Concurrency: concurrency,
+		Recipes:     toolRecipes,
+		Stdout:      &stdout,
+		Stderr:      io.Discard,
+	}
+
+	var running, maxRunning atomic.Int32
+
+	install := scriptInstaller("#!/bin/sh\n", nil)
+	fetcher.RegisterInstaller("script", InstallerFunc(func(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			prev := maxRunning.Load()
+			if current <= prev || maxRunning.CompareAndSwap(prev, current) {
+				break
+			}
+		}
+
+		// block until the other installs started, so that a missing limit shows up as too many running at once
+		deadline := time.Now().Add(time.Second)
+		for running.Load() < concurrency && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+
+		for i := range 3 {
+			fmt.Fprintf(tool.Stdout, "%s %d\n", tool.Name, i)
+			time.Sleep(time.Millisecond)
+		}
+
+		return install(ctx, tool, storeDir)
+	}))
+
+	err = fetcher.FetchAll(context.Background())
+	require.NoError(t, err)
+
+	assert.EqualValues(t, concurrency, maxRunning.Load())
+
+	for _, name := range toolnames {
+		assert.Contains(t, stdout.String(), fmt.Sprintf("%s 0\n%s 1\n%s 2\n", name, name, name))
+	}
+}
+
+func TestToolFetcher_DefaultRecipe(t *testing.T) {
+	entries := toolfile.Entries{
+		"gotestsum": {Name: "gotestsum", Version: "1.12.0", Scheme: toolfile.SchemeGo, Location: "gotest.tools/gotestsum"},
+		"foo":       {Name: "foo", Version: "1.0.0", Scheme: "npm", Location: "foo"},
+	}
+
+	run := &fetchRun{entries: entries}
+
+	tool, err := run.tool("gotestsum")
+	require.NoError(t, err)
+	require.Equal(t, recipes.Recipe{
+		Name: "gotestsum",
+		Src:  recipes.Source{Type: recipes.SourceTypeGoInstall, URLTemplate: "gotest.tools/gotestsum"},
+	}, *tool.Recipe)
+
+	_, err = run.tool("foo")
+	require.ErrorIs(t, err, ErrUnknownTool)
+}
+
+func TestToolFetcher_ConcurrentFetch(t *testing.T) {
+	cwd := t.TempDir()
+
+	toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+	err := os.WriteFile(toolfilePath, []byte("foo: script://foo@1.0.0\nbar: script://bar@1.0.0\n"), 0o644)
+	require.NoError(t, err)
+
+	var installs atomic.Int32
+
+	// every fetcher reads the version and lock file on its own, like separate processes would
+	newFetcher := func() *ToolFetcher {
+		fetcher := &ToolFetcher{
+			VersionFile: toolfilePath,
+			BinDir:      path.Join(cwd, ".bin"),
+			Recipes: []recipes.Recipe{
+				{Name: "foo", Src: recipes.Source{Type: "script"}},
+				{Name: "bar", Src: recipes.Source{Type: "script"}},
+			},
+		}
+
+		install := scriptInstaller("#!/bin/sh\n", &installs)
+		fetcher.RegisterInstaller("script", InstallerFunc(func(ctx context.Context, tool *Tool, storeDir string) (InstallResult, error) {
+			time.Sleep(50 * time.Millisecond)
+			return install(ctx, tool, storeDir)
+		}))
+
+		return fetcher
+	}
+
+	var wg sync.WaitGroup
+	for _, toolname := range []string{"foo", "foo", "bar", "bar"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, newFetcher().Fetch(context.Background(), toolname))
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 2, installs.Load())
+	assert.FileExists(t, path.Join(cwd, ".bin", "foo"))
+	assert.FileExists(t, path.Join(cwd, ".bin", "bar"))
+
+	lock, err := lockfile.ReadFile(toolfilePath + ".lock")
+	require.NoError(t, err)
+
+	_, ok := lock.Get("foo", "1.0.0", currentPlatform())
+	assert.True(t, ok)
+
+	_, ok = lock.Get("bar", "1.0.0", currentPlatform())
+	assert.True(t, ok)
+}
+
+func TestToolFetcher_SharedStore(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("HOME", cacheDir)
+
+	var installs atomic.Int32
+
+	newFetcher := func(cwd string) *ToolFetcher {
+		toolfilePath := path.Join(cwd, "TOOL_VERSIONS")
+		err := os.WriteFile(toolfilePath, []byte("tool: script://tool@1.0.0\n"), 0o644)
+		require.NoError(t, err)
+
+		fetcher := &ToolFetcher{
+			VersionFile: toolfilePath,
+			BinDir:      path.Join(cwd, ".bin"),
+			SharedStore: true,
+			Recipes:     []recipes.Recipe{{Name: "tool", Src: recipes.Source{Type: "script"}}},
+		}
+
+		fetcher.RegisterInstaller("script", scriptInstaller("#!/bin/sh\n", &installs))
+
+		return fetcher
+	}
+
+	projectA, projectB := t.TempDir(), t.TempDir()
+
+	require.NoError(t, newFetcher(projectA).FetchAll(context.Background()))
+	require.NoError(t, newFetcher(projectB).FetchAll(context.Background()))
+
+	assert.EqualValues(t, 1, installs.Load())
+
+	fetcher := newFetcher(projectA)
+
+	statuses, err := fetcher.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Installed)
+
+	userCacheDir, err := os.UserCacheDir()
+	require.NoError(t, err)
+
+	for _, project := range []string{projectA, projectB} {
+		target, err := os.Readlink(path.Join(project, ".bin", "tool"))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(target, path.Join(userCacheDir, "toolfetcher", "store")+"/"), target)
+	}
+
+	_, err = fetcher.Prune(context.Background(), PruneOptions{})
+	assert.ErrorIs(t, err, ErrPruneSharedStore)
+
+	report, err := fetcher.Prune(context.Background(), PruneOptions{KeepUsedWithin: time.Hour})
+	require.NoError(t, err)
+	assert.Empty(t, report.Removed)
+}
@@ -11,11 +11,21 @@ import (
 var ErrMissingName = errors.New("missing tool name")
 var ErrMissingVersion = errors.New("missing tool version")
 
+const (
+	SchemeGo             = "go"
+	SchemeGitHubReleases = "github-releases"
+)
+
 type Entries map[string]Entry
 
 type Entry struct {
 	Name    string
 	Version string
+
+	// Scheme is the part of the source before "://", e.g. "go" or "github-releases".
+	Scheme string
+	// Location is the part of the source after "://", e.g. "gotest.tools/gotestsum".
+	Location string
 }
 
 func ParseToolFile(r io.Reader) (Entries, error) {
@@ -78,5 +88,18 @@ func parseLine(line []byte, lineNum int) (Entry, error) {
 		return entry, fmt.Errorf("%w for tool %s on line %d: no version found", ErrMissingVersion, entry.Name, lineNum)
 	}
 
+	if versionMarkerIndex > firstColonIndex {
+		entry.Scheme, entry.Location = parseSource(bytes.TrimSpace(line[firstColonIndex+1 : versionMarkerIndex]))
+	}
+
 	return entry, nil
 }
+
+func parseSource(src []byte) (string, string) {
+	scheme, location, found := bytes.Cut(src, []byte("://"))
+	if !found {
+		return "", string(src)
+	}
+
+	return string(scheme), string(location)
+}
@@ -24,7 +24,16 @@ staticcheck: go://honnef.co/go/tools/cmd/staticcheck@2024.1.1
 # OpenAPI
 oapi-codegen: github-releases://oapi-codegen/oapi-codegen@2.4.1
 `,
-			entries: Entries{"staticcheck": {Name: "staticcheck", Version: "2024.1.1"}, "oapi-codegen": {Name: "oapi-codegen", Version: "2.4.1"}},
+			entries: Entries{
+				"staticcheck":  {Name: "staticcheck", Version: "2024.1.1", Scheme: "go", Location: "honnef.co/go/tools/cmd/staticcheck"},
+				"oapi-codegen": {Name: "oapi-codegen", Version: "2.4.1", Scheme: "github-releases", Location: "oapi-codegen/oapi-codegen"},
+			},
+		},
+
+		{
+			name:     "Valid Tool File/No Scheme",
+			contents: `gotestsum: gotest.tools/gotestsum@1.12.0`,
+			entries:  Entries{"gotestsum": {Name: "gotestsum", Version: "1.12.0", Location: "gotest.tools/gotestsum"}},
 		},
 
 		{